@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kazimanzurrashid/consents-api-go/export"
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+const (
+	exitBroken = 1
+	exitError  = 2
+)
+
+func runCommand(db *sql.DB, args []string) int {
+	switch args[0] {
+	case "verify-chain":
+		return verifyChain(db, args[1:], os.Stdout, os.Stderr)
+	case "export-user":
+		return exportUser(db, args[1:], os.Stdout, os.Stderr)
+	case "purge-events":
+		return purgeEvents(db, args[1:], os.Stdout, os.Stderr)
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return exitError
+	}
+}
+
+func verifyChain(db *sql.DB, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify-chain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	userID := flags.String("user", "", "verify only the chain of this user")
+	tenantID := flags.String("tenant", services.DefaultTenant, "tenant of the users")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	tenantGiven := false
+
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "tenant" {
+			tenantGiven = true
+		}
+	})
+
+	ctx := services.WithTenant(context.Background(), *tenantID)
+	es := services.NewEvent(db, nil)
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	if *userID != "" {
+		res, err := es.VerifyChain(ctx, *userID)
+
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "verify chain error: %v\n", err)
+			return exitError
+		}
+
+		_ = encoder.Encode(res)
+
+		if !res.Valid {
+			return exitBroken
+		}
+
+		return 0
+	}
+
+	if !tenantGiven {
+		return verifyTenantChains(db, es, encoder, stderr)
+	}
+
+	res, err := es.VerifyChains(ctx)
+
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "verify chains error: %v\n", err)
+		return exitError
+	}
+
+	_ = encoder.Encode(res)
+
+	if !res.Valid {
+		return exitBroken
+	}
+
+	return 0
+}
+
+func verifyTenantChains(
+	db *sql.DB,
+	es services.Event,
+	encoder *json.Encoder,
+	stderr io.Writer) int {
+
+	tenants, err := services.NewTenant(db).List(context.Background())
+
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "list tenants error: %v\n", err)
+		return exitError
+	}
+
+	audit := models.ChainAudit{
+		Valid:   true,
+		Tenants: make([]models.TenantChainReport, 0, len(tenants)),
+	}
+
+	for _, tenantID := range tenants {
+		res, err := es.VerifyChains(
+			services.WithTenant(context.Background(), tenantID))
+
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "verify chains error: %s: %v\n", tenantID, err)
+			return exitError
+		}
+
+		audit.Tenants = append(audit.Tenants, models.TenantChainReport{
+			TenantID:    tenantID,
+			ChainReport: *res,
+		})
+
+		if !res.Valid {
+			audit.Valid = false
+		}
+	}
+
+	_ = encoder.Encode(audit)
+
+	if !audit.Valid {
+		return exitBroken
+	}
+
+	return 0
+}
+
+func exportUser(db *sql.DB, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export-user", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	userID := flags.String("user", "", "user to export")
+	tenantID := flags.String("tenant", services.DefaultTenant, "tenant of the user")
+	format := flags.String("format", models.ExportJSON, "export format, json or zip")
+	out := flags.String("out", "", "file to write the export to, defaults to stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if *userID == "" {
+		_, _ = fmt.Fprintln(stderr, "-user is required")
+		return exitError
+	}
+
+	if *format != models.ExportJSON && *format != models.ExportZip {
+		_, _ = fmt.Fprintf(stderr, "unsupported export format %q\n", *format)
+		return exitError
+	}
+
+	ctx := services.WithTenant(context.Background(), *tenantID)
+	bundle, err := services.NewUser(db, nil).Export(ctx, *userID)
+
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "export user error: %v\n", err)
+		return exitError
+	}
+
+	w := stdout
+
+	if *out != "" {
+		file, err := os.Create(*out)
+
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "export file create error: %v\n", err)
+			return exitError
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		w = file
+	}
+
+	if err := export.Write(w, bundle, *format); err != nil {
+		_, _ = fmt.Fprintf(stderr, "export write error: %v\n", err)
+		return exitError
+	}
+
+	return 0
+}
+
+func purgeEvents(db *sql.DB, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("purge-events", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	dryRun := flags.Bool("dry-run", false, "report what would be purged without deleting")
+	batchSize := flags.Int("batch", services.DefaultPurgeBatchSize, "events deleted per transaction")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	res, err := services.NewRetention(db).Purge(
+		context.Background(),
+		services.PurgeOptions{BatchSize: *batchSize, DryRun: *dryRun})
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	if res != nil {
+		_ = encoder.Encode(res)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "purge events error: %v\n", err)
+		return exitError
+	}
+
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("verifyChain", func() {
+	chainColumns := []string{
+		"id",
+		"event_hash",
+		"chain_start",
+		"id",
+		"consent_id",
+		"sequence",
+		"enabled",
+		"phone_number",
+		"occurred_at",
+		"created_at",
+		"previous_hash",
+		"hash",
+		"purged",
+	}
+
+	Context("without tenant", func() {
+		var (
+			mock   sqlmock.Sqlmock
+			code   int
+			stdout bytes.Buffer
+			audit  models.ChainAudit
+		)
+
+		BeforeEach(func() {
+			db, m, err := sqlmock.New()
+			Expect(err).To(BeNil())
+			mock = m
+
+			mock.ExpectQuery("FROM \"tenants\" ORDER BY id").
+				WillReturnRows(mock.NewRows([]string{"id"}).
+					AddRow("acme").
+					AddRow(services.DefaultTenant))
+
+			heads := map[string]string{"acme": "", services.DefaultTenant: "broken"}
+
+			for _, tenantID := range []string{"acme", services.DefaultTenant} {
+				mock.ExpectBegin()
+				mock.ExpectExec("set_config").
+					WithArgs(tenantID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("ORDER BY u.id, e.sequence").
+					WillReturnRows(mock.NewRows(chainColumns).AddRow(
+						"user", heads[tenantID], 0, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+				mock.ExpectCommit()
+			}
+
+			stdout.Reset()
+			code = verifyChain(db, nil, &stdout, &bytes.Buffer{})
+			_ = json.Unmarshal(stdout.Bytes(), &audit)
+		})
+
+		It("reports every tenant", func() {
+			Expect(code).To(Equal(exitBroken))
+			Expect(audit.Valid).To(BeFalse())
+			Expect(audit.Tenants).To(HaveLen(2))
+			Expect(audit.Tenants[0].TenantID).To(Equal("acme"))
+			Expect(audit.Tenants[0].Valid).To(BeTrue())
+			Expect(audit.Tenants[1].TenantID).To(Equal(services.DefaultTenant))
+			Expect(audit.Tenants[1].Valid).To(BeFalse())
+			Expect(audit.Tenants[1].Broken.BrokenAt.Reason).
+				To(Equal(services.ChainHeadMismatch))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+const (
+	dataFile    = "export.json"
+	summaryFile = "summary.html"
+)
+
+var summary = template.Must(template.New(summaryFile).Parse(summaryPage))
+
+func Filename(userID string, format string) string {
+	return fmt.Sprintf("user-%s.%s", userID, format)
+}
+
+func ContentType(format string) string {
+	if format == models.ExportZip {
+		return "application/zip"
+	}
+
+	return "application/json;charset=utf-8"
+}
+
+func Write(w io.Writer, bundle *models.UserExport, format string) error {
+	if format == models.ExportZip {
+		return writeZip(w, bundle)
+	}
+
+	return writeJSON(w, bundle)
+}
+
+func writeJSON(w io.Writer, bundle *models.UserExport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(bundle)
+}
+
+func writeZip(w io.Writer, bundle *models.UserExport) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range []struct {
+		name  string
+		write func(io.Writer, *models.UserExport) error
+	}{
+		{dataFile, writeJSON},
+		{summaryFile, writeSummary},
+	} {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: bundle.GeneratedAt,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if err := file.write(entry, bundle); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeSummary(w io.Writer, bundle *models.UserExport) error {
+	return summary.Execute(w, bundle)
+}
+
+const summaryPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Personal data export</title>
+  <style>
+    body { font-family: sans-serif; margin: 2rem; }
+    table { border-collapse: collapse; margin-bottom: 2rem; }
+    th, td { border: 1px solid #ccc; padding: .25rem .5rem; text-align: left; }
+  </style>
+</head>
+<body>
+  <h1>Personal data export</h1>
+  <p>Generated at {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}. The complete data is in export.json.</p>
+
+  <h2>Profile</h2>
+  <table>
+    <tr><th>ID</th><td>{{.User.ID}}</td></tr>
+    <tr><th>Email</th><td>{{.User.Email}}</td></tr>
+    <tr><th>External ID</th><td>{{.User.ExternalID}}</td></tr>
+    {{range .User.Phones}}<tr><th>Phone</th><td>{{.Number}}{{if .Primary}} (primary){{end}}</td></tr>
+    {{end}}{{range .User.Identifiers}}<tr><th>{{.Namespace}}</th><td>{{.ID}}</td></tr>
+    {{end}}{{range .Devices}}<tr><th>Device</th><td>{{.ID}}</td></tr>
+    {{end}}<tr><th>Legal hold</th><td>{{with .LegalHold.PlacedAt}}placed at {{.Format "2006-01-02 15:04:05 MST"}}{{else}}none{{end}}</td></tr>
+  </table>
+
+  <h2>Current consents</h2>
+  <table>
+    <tr><th>Consent</th><th>Enabled</th></tr>
+    {{range .User.Consents}}<tr><td>{{.ID}}</td><td>{{.Enabled}}</td></tr>
+    {{end}}
+  </table>
+
+  <h2>Consent history</h2>
+  <table>
+    <tr><th>#</th><th>Consent</th><th>Enabled</th><th>Phone</th><th>Occurred at</th><th>Recorded at</th><th>Receipt</th><th>Merged from</th></tr>
+    {{range .Events}}<tr><td>{{.Sequence}}</td><td>{{.ConsentID}}</td><td>{{.Enabled}}</td><td>{{.PhoneNumber}}</td><td>{{.OccurredAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{.ReceiptID}}</td><td>{{template "origin" .MergedFrom}}</td></tr>
+    {{end}}
+  </table>
+
+  <h2>Purged events</h2>
+  <table>
+    <tr><th>#</th><th>Event</th><th>Purged at</th><th>Merged from</th></tr>
+    {{range .Tombstones}}<tr><td>{{.Sequence}}</td><td>{{.EventID}}</td><td>{{.PurgedAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{template "origin" .MergedFrom}}</td></tr>
+    {{end}}
+  </table>
+
+  <h2>Consent receipts</h2>
+  <table>
+    <tr><th>ID</th><th>Issued at</th><th>Signing key</th></tr>
+    {{range .Receipts}}<tr><td>{{.ID}}</td><td>{{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</td><td>{{.KeyID}}</td></tr>
+    {{end}}
+  </table>
+
+  <h2>Email history</h2>
+  <table>
+    <tr><th>Changed at</th><th>From</th><th>To</th></tr>
+    {{range .EmailChanges}}<tr><td>{{with .ChangedAt}}{{.Format "2006-01-02 15:04:05 MST"}}{{else}}before history was recorded{{end}}</td><td>{{.PreviousEmail}}</td><td>{{.Email}}</td></tr>
+    {{end}}
+  </table>
+
+  <h2>Merged accounts</h2>
+  <table>
+    <tr><th>Account</th><th>Email</th><th>External ID</th><th>Device</th><th>Merged at</th></tr>
+    {{range .Merges}}<tr><td>{{.FromID}}</td><td>{{.Email}}</td><td>{{.ExternalID}}</td><td>{{.DeviceID}}</td><td>{{.MergedAt.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+    {{end}}
+  </table>
+</body>
+</html>
+{{define "origin"}}{{with .}}{{if .DeviceID}}device {{.DeviceID}}{{else}}account {{.UserID}}{{end}}{{end}}{{end}}`
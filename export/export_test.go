@@ -0,0 +1,141 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("Export", func() {
+	var (
+		bundle *models.UserExport
+		buffer *bytes.Buffer
+		e      error
+	)
+
+	BeforeEach(func() {
+		generatedAt := time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC)
+		bundle = &models.UserExport{
+			GeneratedAt: generatedAt,
+			TenantID:    "default",
+			User: models.User{
+				ID:    "7b5a3155-7a73-42de-b87e-23f50a10180a",
+				Email: "<user@example.com>",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			},
+			Events: []models.ExportEvent{
+				{
+					Event: models.Event{
+						ID:         "0d5f6c8e-3b0a-4c55-9f0e-8a7d3f1b2c4d",
+						ConsentID:  models.ConsentEmail,
+						Sequence:   1,
+						Enabled:    true,
+						OccurredAt: generatedAt,
+						CreatedAt:  generatedAt,
+					},
+					ReceiptID: "3f2a9c1e-8d4b-4e6f-a1c2-5b7d9e0f1a2b",
+					MergedFrom: &models.EventOrigin{
+						UserID:   "9c1d2e3f-4a5b-4c6d-8e7f-0a1b2c3d4e5f",
+						DeviceID: "<device-1>",
+					},
+				},
+			},
+			Tombstones: []models.ExportTombstone{
+				{
+					EventID:  "5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9",
+					Sequence: 2,
+					Hash:     "hash",
+					PurgedAt: generatedAt,
+				},
+			},
+			EmailChanges: []models.EmailChange{
+				{Email: "<user@example.com>"},
+			},
+		}
+		buffer = &bytes.Buffer{}
+	})
+
+	Describe("json", func() {
+		BeforeEach(func() {
+			e = Write(buffer, bundle, models.ExportJSON)
+		})
+
+		It("writes bundle", func() {
+			var res models.UserExport
+
+			Expect(e).To(BeNil())
+			Expect(json.Unmarshal(buffer.Bytes(), &res)).To(Succeed())
+			Expect(res.User.ID).To(Equal(bundle.User.ID))
+			Expect(res.Events[0].ReceiptID).To(Equal(bundle.Events[0].ReceiptID))
+			Expect(res.Events[0].MergedFrom).To(Equal(bundle.Events[0].MergedFrom))
+			Expect(res.Tombstones).To(Equal(bundle.Tombstones))
+			Expect(res.LegalHold.Held).To(BeFalse())
+		})
+	})
+
+	Describe("zip", func() {
+		var files map[string]string
+
+		BeforeEach(func() {
+			e = Write(buffer, bundle, models.ExportZip)
+
+			archive, err := zip.NewReader(
+				bytes.NewReader(buffer.Bytes()),
+				int64(buffer.Len()))
+
+			if err != nil {
+				panic(err)
+			}
+
+			files = make(map[string]string)
+
+			for _, file := range archive.File {
+				reader, err := file.Open()
+
+				if err != nil {
+					panic(err)
+				}
+
+				content, err := io.ReadAll(reader)
+
+				if err != nil {
+					panic(err)
+				}
+
+				files[file.Name] = string(content)
+			}
+		})
+
+		It("contains json bundle and html summary", func() {
+			Expect(e).To(BeNil())
+			Expect(files).To(HaveKey("export.json"))
+			Expect(files).To(HaveKey("summary.html"))
+		})
+
+		It("escapes user data in summary", func() {
+			Expect(files["summary.html"]).To(ContainSubstring("&lt;user@example.com&gt;"))
+			Expect(files["summary.html"]).To(ContainSubstring("before history was recorded"))
+		})
+
+		It("lists purged events and where merged events came from", func() {
+			Expect(files["summary.html"]).To(ContainSubstring("5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9"))
+			Expect(files["summary.html"]).To(ContainSubstring("device &lt;device-1&gt;"))
+			Expect(files["summary.html"]).To(ContainSubstring("<th>Legal hold</th><td>none</td>"))
+		})
+	})
+
+	Describe("Filename", func() {
+		It("names file after user and format", func() {
+			Expect(Filename("abc", models.ExportZip)).To(Equal("user-abc.zip"))
+		})
+	})
+})
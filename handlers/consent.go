@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+type Consent struct {
+	srv services.Event
+}
+
+func NewConsent(srv services.Event) *Consent {
+	return &Consent{srv}
+}
+
+func (h *Consent) Detail(w http.ResponseWriter, r *http.Request) {
+	userID, consentID, ok := consentPath(w, r)
+
+	if !ok {
+		return
+	}
+
+	h.writeState(w, r, userID, consentID)
+}
+
+func (h *Consent) Update(w http.ResponseWriter, r *http.Request) {
+	userID, consentID, ok := consentPath(w, r)
+
+	if !ok {
+		return
+	}
+
+	var req models.ConsentUpdateRequest
+
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	res, err := h.srv.Create(r.Context(), &models.EventCreateRequest{
+		User: &models.EventCreateUser{ID: userID},
+		Consents: &[]models.Consent{
+			{
+				ID:         consentID,
+				Enabled:    *req.Enabled,
+				OccurredAt: req.OccurredAt,
+			},
+		},
+		IfMatch: ifMatch(r),
+	})
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrConsentNotOffered) {
+		writeError(w, http.StatusUnprocessableEntity, "Consent is not offered")
+		return
+	}
+
+	if errors.Is(err, services.ErrPhoneRequired) {
+		writeError(w, http.StatusUnprocessableEntity, "Primary phone number is required")
+		return
+	}
+
+	if errors.Is(err, services.ErrPreconditionFailed) {
+		writeError(w, http.StatusPreconditionFailed, "User consents were modified")
+		return
+	}
+
+	if errors.Is(err, services.ErrConflict) {
+		writeError(w, http.StatusConflict, "Conflicting consent change")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	for _, state := range res.States {
+		if state.ID == consentID {
+			w.Header().Set("ETag", formatETag(res.Version))
+			writeSuccess(w, http.StatusOK, state)
+			return
+		}
+	}
+
+	writeServerError(w, r, services.ErrNotFound)
+}
+
+func (h *Consent) writeState(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID string,
+	consentID string) {
+	state, err := h.srv.Consent(r.Context(), userID, consentID)
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Consent not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, state)
+}
+
+func consentPath(
+	w http.ResponseWriter,
+	r *http.Request) (string, string, bool) {
+	vars := mux.Vars(r)
+
+	user := models.EventCreateUser{ID: vars["id"]}
+	consent := models.Consent{ID: vars["consentId"]}
+
+	if user.Validate() != nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return "", "", false
+	}
+
+	if consent.Validate() != nil {
+		writeError(w, http.StatusNotFound, "Consent not found")
+		return "", "", false
+	}
+
+	return user.ID, consent.ID, true
+}
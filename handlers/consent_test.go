@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Consent", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	var (
+		srv       *fakeEventService
+		consentID string
+		body      io.Reader
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func(method string, handle func(*Consent) http.HandlerFunc) {
+		req, err := http.NewRequest(
+			method,
+			fmt.Sprintf("/v1/users/%v/consents/%v", id, consentID),
+			body)
+
+		if err != nil {
+			panic(err)
+		}
+
+		req = mux.SetURLVars(req, map[string]string{
+			"id":        id,
+			"consentId": consentID,
+		})
+
+		recorder = httptest.NewRecorder()
+		handle(NewConsent(srv)).ServeHTTP(recorder, req)
+	}
+
+	BeforeEach(func() {
+		srv = &fakeEventService{
+			state: &models.ConsentState{
+				ID:        models.ConsentEmail,
+				Enabled:   true,
+				ChangedAt: time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC),
+			},
+		}
+		consentID = models.ConsentEmail
+		body = nil
+	})
+
+	Describe("Detail", func() {
+		detail := func(h *Consent) http.HandlerFunc { return h.Detail }
+
+		Context("existent", func() {
+			var res models.ConsentState
+
+			BeforeEach(func() {
+				serve(http.MethodGet, detail)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns consent state", func() {
+				Expect(res.ID).To(Equal(models.ConsentEmail))
+				Expect(res.Enabled).To(BeTrue())
+				Expect(res.ChangedAt).To(Equal(srv.state.ChangedAt))
+			})
+
+			It("returns http status code Ok", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("never set", func() {
+			BeforeEach(func() {
+				srv.state = nil
+				srv.stateErr = services.ErrNotFound
+
+				serve(http.MethodGet, detail)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				Expect(recorder.Body.String()).To(ContainSubstring("Consent not found"))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.state = nil
+				srv.stateErr = services.ErrUserNotFound
+
+				serve(http.MethodGet, detail)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				Expect(recorder.Body.String()).To(ContainSubstring("User not found"))
+			})
+		})
+
+		Context("malformed consent", func() {
+			BeforeEach(func() {
+				consentID = "Push Notifications"
+
+				serve(http.MethodGet, detail)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		update := func(h *Consent) http.HandlerFunc { return h.Update }
+
+		Context("success", func() {
+			BeforeEach(func() {
+				body = strings.NewReader(`{"enabled": false}`)
+
+				serve(http.MethodPut, update)
+			})
+
+			It("records a single consent event", func() {
+				Expect(srv.created.User.ID).To(Equal(id))
+				Expect(*srv.created.Consents).To(Equal([]models.Consent{
+					{ID: models.ConsentEmail, Enabled: false},
+				}))
+			})
+
+			It("returns http status code Ok", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("back-dated behind a newer event", func() {
+			var res models.ConsentState
+
+			BeforeEach(func() {
+				consentID = models.ConsentSMS
+				srv.states = []models.ConsentState{
+					{
+						ID:          models.ConsentSMS,
+						Enabled:     true,
+						PhoneNumber: "+14155550100",
+						ChangedAt:   time.Date(2026, time.October, 2, 10, 0, 0, 0, time.UTC),
+					},
+				}
+				body = strings.NewReader(`{"enabled": false, "occurred_at": "2026-10-01T10:00:00Z"}`)
+
+				serve(http.MethodPut, update)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns current consent state instead of the written event", func() {
+				Expect(res).To(Equal(srv.states[0]))
+			})
+
+			It("returns http status code Ok", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("missing enabled", func() {
+			BeforeEach(func() {
+				body = strings.NewReader(`{}`)
+
+				serve(http.MethodPut, update)
+			})
+
+			It("does not record event", func() {
+				Expect(srv.created).To(BeNil())
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrUserNotFound
+				body = strings.NewReader(`{"enabled": true}`)
+
+				serve(http.MethodPut, update)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
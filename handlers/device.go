@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+type Device struct {
+	srv services.User
+}
+
+func NewDevice(srv services.User) *Device {
+	return &Device{srv}
+}
+
+func (h *Device) Detail(w http.ResponseWriter, r *http.Request) {
+	device := models.EventCreateDevice{ID: mux.Vars(r)["deviceId"]}
+
+	if device.Validate() != nil {
+		writeError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	res, err := h.srv.Device(r.Context(), device.ID)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(res.Version))
+
+	if ifNoneMatch(r, res.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, res)
+}
+
+func (h *Device) Identify(w http.ResponseWriter, r *http.Request) {
+	device := models.EventCreateDevice{ID: mux.Vars(r)["deviceId"]}
+
+	if device.Validate() != nil {
+		writeError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	var req models.DeviceIdentifyRequest
+
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	user, err := h.srv.Identify(r.Context(), device.ID, &req)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusUnprocessableEntity, "User does not exist")
+		return
+	}
+
+	if errors.Is(err, services.ErrChainBroken) {
+		writeError(w, http.StatusConflict, "Event chain is broken")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(user.Version))
+	writeSuccess(w, http.StatusOK, versionOf(r).user(user))
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Device", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	var (
+		srv         *fakeUserService
+		body        string
+		ifNoneMatch string
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func(method string, handle func(*Device) http.HandlerFunc) {
+		req, err := http.NewRequest(method, "/", strings.NewReader(body))
+
+		if err != nil {
+			panic(err)
+		}
+
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		req = mux.SetURLVars(req, map[string]string{
+			"deviceId": "cookie-4f1c",
+		})
+
+		recorder = httptest.NewRecorder()
+		handle(NewDevice(srv)).ServeHTTP(recorder, req)
+	}
+
+	BeforeEach(func() {
+		srv = &fakeUserService{
+			user: &models.User{
+				ID:       id,
+				Email:    "user@example.com",
+				Consents: make([]models.Consent, 0),
+				Version:  "abc",
+			},
+			device: &models.Device{
+				ID: "cookie-4f1c",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+				Version: "def",
+			},
+		}
+		body = `{"user_id":"` + id + `"}`
+		ifNoneMatch = ""
+	})
+
+	Describe("Detail", func() {
+		detail := func(h *Device) http.HandlerFunc { return h.Detail }
+
+		Context("existent", func() {
+			var res models.Device
+
+			BeforeEach(func() {
+				serve(http.MethodGet, detail)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns consents of device", func() {
+				Expect(res.ID).To(Equal("cookie-4f1c"))
+				Expect(res.Consents).To(HaveLen(1))
+			})
+
+			It("returns version of consents", func() {
+				Expect(recorder.Header().Get("ETag")).To(Equal(`"def"`))
+			})
+		})
+
+		Context("unchanged", func() {
+			BeforeEach(func() {
+				ifNoneMatch = `"def"`
+				serve(http.MethodGet, detail)
+			})
+
+			It("returns http status code NotModified", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotModified))
+			})
+		})
+
+		Context("non-existent", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+				serve(http.MethodGet, detail)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Identify", func() {
+		identify := func(h *Device) http.HandlerFunc { return h.Identify }
+
+		Context("success", func() {
+			var res models.User
+
+			BeforeEach(func() {
+				serve(http.MethodPost, identify)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns identified user", func() {
+				Expect(res.ID).To(Equal(id))
+			})
+
+			It("returns http status code OK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("invalid body", func() {
+			BeforeEach(func() {
+				body = `{"user_id":"foo"}`
+				serve(http.MethodPost, identify)
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("non-existent device", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+				serve(http.MethodPost, identify)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrUserNotFound
+				serve(http.MethodPost, identify)
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+	})
+})
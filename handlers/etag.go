@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+func formatETag(version string) string {
+	return `"` + version + `"`
+}
+
+func parseETags(header string, weak bool) []string {
+	tags := make([]string, 0)
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				tags = append(tags, tag)
+				continue
+			}
+
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		tag = strings.Trim(tag, `"`)
+
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func ifMatch(r *http.Request) []string {
+	return parseETags(r.Header.Get("If-Match"), false)
+}
+
+func ifNoneMatch(r *http.Request, version string) bool {
+	for _, tag := range parseETags(r.Header.Get("If-None-Match"), true) {
+		if tag == "*" || tag == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/logging"
 	"github.com/kazimanzurrashid/consents-api-go/models"
 	"github.com/kazimanzurrashid/consents-api-go/services"
 )
@@ -19,20 +23,102 @@ func NewEvent(srv services.Event) *Event {
 func (h *Event) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.EventCreateRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusUnprocessableEntity, "Malformed request body")
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	if req.User != nil {
+		logging.SetUserID(r.Context(), req.User.ID)
+	}
+
+	req.IfMatch = ifMatch(r)
+
+	res, err := h.srv.Create(r.Context(), &req)
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusUnprocessableEntity, "User does not exist")
+		return
+	}
+
+	if errors.Is(err, services.ErrConsentNotOffered) {
+		writeError(w, http.StatusUnprocessableEntity, "Consent is not offered")
+		return
+	}
+
+	if errors.Is(err, services.ErrPhoneRequired) {
+		writeError(w, http.StatusUnprocessableEntity, "Primary phone number is required")
+		return
+	}
+
+	if errors.Is(err, services.ErrPreconditionFailed) {
+		writeError(w, http.StatusPreconditionFailed, "User consents were modified")
+		return
+	}
+
+	if errors.Is(err, services.ErrConflict) {
+		writeError(w, http.StatusConflict, "Conflicting consent change")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	w.Header().Set("ETag", formatETag(res.Version))
+
+	if len(res.Events) == 0 {
+		writeSuccess(w, http.StatusOK, res)
+		return
+	}
+
+	w.Header().Set(
+		"Location",
+		fmt.Sprintf("/%s/events/%s", versionOf(r).name, res.Events[0].ID))
+	writeSuccess(w, http.StatusCreated, res)
+}
+
+func (h *Event) Detail(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	event, err := h.srv.Detail(r.Context(), id)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
 		return
 	}
 
-	if err := h.srv.Create(r.Context(), &req); err != nil {
-		writeError(w, http.StatusUnprocessableEntity, "Invalid request")
+	writeSuccess(w, http.StatusOK, event)
+}
+
+func (h *Event) Chain(w http.ResponseWriter, r *http.Request) {
+	res, err := h.srv.VerifyChain(r.Context(), mux.Vars(r)["id"])
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, res)
+}
+
+func (h *Event) Chains(w http.ResponseWriter, r *http.Request) {
+	res, err := h.srv.VerifyChains(r.Context())
+
+	if err != nil {
+		writeServerError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	writeSuccess(w, http.StatusOK, res)
 }
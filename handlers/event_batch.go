@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+type eventBatchItemResult struct {
+	Status    int            `json:"status"`
+	Events    []models.Event `json:"events,omitempty"`
+	Changed   []string       `json:"changed,omitempty"`
+	ReceiptID string         `json:"receipt_id,omitempty"`
+	Errors    []string       `json:"errors,omitempty"`
+}
+
+type eventBatchResult struct {
+	Items []eventBatchItemResult `json:"items"`
+}
+
+func (h *Event) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req models.EventBatchRequest
+
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	res := eventBatchResult{
+		Items: make([]eventBatchItemResult, len(req.Items)),
+	}
+
+	valid := models.EventBatchRequest{Mode: req.Mode}
+	indexes := make([]int, 0, len(req.Items))
+
+	for index, item := range req.Items {
+		if err := item.Validate(); err != nil {
+			res.Items[index] = eventBatchItemResult{
+				Status: http.StatusUnprocessableEntity,
+				Errors: []string{err.Error()},
+			}
+			continue
+		}
+
+		valid.Items = append(valid.Items, item)
+		indexes = append(indexes, index)
+	}
+
+	rejected := len(valid.Items) < len(req.Items)
+
+	if len(valid.Items) > 0 && !(rejected && req.Atomic()) {
+		items, err := h.srv.CreateBatch(r.Context(), &valid)
+
+		if errors.Is(err, services.ErrConsentNotOffered) {
+			writeError(w, http.StatusUnprocessableEntity, "Consent is not offered")
+			return
+		}
+
+		if err != nil && !errors.Is(err, services.ErrBatchRejected) {
+			writeServerError(w, r, err)
+			return
+		}
+
+		for index, item := range items {
+			res.Items[indexes[index]] = batchItemResult(item)
+			rejected = rejected || item.Err != nil
+		}
+	}
+
+	if !req.Atomic() {
+		writeSuccess(w, http.StatusMultiStatus, res)
+		return
+	}
+
+	if rejected {
+		for index, item := range res.Items {
+			if item.Status < http.StatusBadRequest {
+				res.Items[index] = eventBatchItemResult{
+					Status: http.StatusFailedDependency,
+					Errors: []string{"Not recorded because another item failed"},
+				}
+			}
+		}
+
+		writeSuccess(w, http.StatusUnprocessableEntity, res)
+		return
+	}
+
+	writeSuccess(w, http.StatusCreated, res)
+}
+
+func batchItemResult(item models.EventBatchItemResult) eventBatchItemResult {
+	if errors.Is(item.Err, services.ErrUserNotFound) {
+		return eventBatchItemResult{
+			Status: http.StatusUnprocessableEntity,
+			Errors: []string{"User does not exist"},
+		}
+	}
+
+	if errors.Is(item.Err, services.ErrConsentNotOffered) {
+		return eventBatchItemResult{
+			Status: http.StatusUnprocessableEntity,
+			Errors: []string{"Consent is not offered"},
+		}
+	}
+
+	if errors.Is(item.Err, services.ErrPhoneRequired) {
+		return eventBatchItemResult{
+			Status: http.StatusUnprocessableEntity,
+			Errors: []string{"Primary phone number is required"},
+		}
+	}
+
+	if item.Err != nil {
+		return eventBatchItemResult{
+			Status: http.StatusInternalServerError,
+			Errors: []string{"Internal server error"},
+		}
+	}
+
+	if len(item.Events) == 0 {
+		return eventBatchItemResult{
+			Status:  http.StatusOK,
+			Changed: item.Changed,
+		}
+	}
+
+	return eventBatchItemResult{
+		Status:    http.StatusCreated,
+		Events:    item.Events,
+		Changed:   item.Changed,
+		ReceiptID: item.ReceiptID,
+	}
+}
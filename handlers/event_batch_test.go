@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Event", func() {
+	Describe("CreateBatch", func() {
+		const (
+			knownUserID   = "00000000-0000-4000-8000-000000000001"
+			unknownUserID = "00000000-0000-4000-8000-000000000002"
+		)
+
+		var (
+			srv        *fakeEventService
+			mode       string
+			unknownID  string
+			statusCode int
+			res        eventBatchResult
+		)
+
+		BeforeEach(func() {
+			srv = &fakeEventService{missingUser: unknownUserID}
+			mode = ""
+			unknownID = unknownUserID
+		})
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/events/batch",
+				strings.NewReader(fmt.Sprintf(`{
+					"mode": %q,
+					"items": [
+						{
+							"user": {"id": %q},
+							"consents": [{"id": "email_notifications", "enabled": false}]
+						},
+						{
+							"user": {"id": %q},
+							"consents": [{"id": "sms_notifications", "enabled": false}]
+						}
+					]
+				}`, mode, knownUserID, unknownID)))
+
+			if err != nil {
+				panic(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			event := NewEvent(srv)
+
+			handler := http.HandlerFunc(event.CreateBatch)
+			handler.ServeHTTP(recorder, req)
+
+			statusCode = recorder.Code
+			res = eventBatchResult{}
+
+			if recorder.Code == http.StatusInternalServerError {
+				return
+			}
+
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		Context("atomic success", func() {
+			BeforeEach(func() {
+				unknownID = knownUserID
+			})
+
+			It("returns created events for every item", func() {
+				Expect(res.Items).To(HaveLen(2))
+				Expect(res.Items[0].Status).To(Equal(http.StatusCreated))
+				Expect(res.Items[1].Events).To(HaveLen(1))
+			})
+
+			It("returns http status code Created", func() {
+				Expect(statusCode).To(Equal(http.StatusCreated))
+			})
+		})
+
+		Context("atomic with non-existent user", func() {
+			It("returns failing and dependent items", func() {
+				Expect(res.Items[0].Status).To(Equal(http.StatusFailedDependency))
+				Expect(res.Items[1].Status).To(Equal(http.StatusUnprocessableEntity))
+				Expect(res.Items[1].Errors[0]).To(Equal("User does not exist"))
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("atomic with invalid item", func() {
+			BeforeEach(func() {
+				unknownID = "foo-bar"
+			})
+
+			It("does not call service", func() {
+				Expect(srv.created).To(BeNil())
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+				Expect(res.Items[1].Status).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("best effort with non-existent user", func() {
+			BeforeEach(func() {
+				mode = "best_effort"
+			})
+
+			It("records remaining items", func() {
+				Expect(res.Items[0].Status).To(Equal(http.StatusCreated))
+				Expect(res.Items[1].Status).To(Equal(http.StatusUnprocessableEntity))
+			})
+
+			It("returns http status code MultiStatus", func() {
+				Expect(statusCode).To(Equal(http.StatusMultiStatus))
+			})
+		})
+
+		Context("best effort with invalid item", func() {
+			BeforeEach(func() {
+				mode = "best_effort"
+				unknownID = "foo-bar"
+			})
+
+			It("records remaining items", func() {
+				Expect(res.Items[0].Status).To(Equal(http.StatusCreated))
+				Expect(res.Items[1].Status).To(Equal(http.StatusUnprocessableEntity))
+				Expect(res.Items[1].Errors).NotTo(BeEmpty())
+			})
+		})
+
+		Context("invalid mode", func() {
+			BeforeEach(func() {
+				mode = "eventually"
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+				Expect(srv.created).To(BeNil())
+			})
+		})
+
+		Context("error in service call", func() {
+			BeforeEach(func() {
+				srv.batchErr = fmt.Errorf("batch error")
+			})
+
+			It("returns http status code InternalServerError", func() {
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	DescribeTable("batch item result",
+		func(err error, status int, message string) {
+			res := batchItemResult(models.EventBatchItemResult{Err: err})
+
+			Expect(res.Status).To(Equal(status))
+			Expect(res.Errors).To(Equal([]string{message}))
+		},
+		Entry("user not found",
+			services.ErrUserNotFound,
+			http.StatusUnprocessableEntity,
+			"User does not exist"),
+		Entry("consent not offered",
+			fmt.Errorf("%w: push_notifications", services.ErrConsentNotOffered),
+			http.StatusUnprocessableEntity,
+			"Consent is not offered"),
+		Entry("phone required",
+			services.ErrPhoneRequired,
+			http.StatusUnprocessableEntity,
+			"Primary phone number is required"),
+		Entry("unexpected",
+			fmt.Errorf("connection reset"),
+			http.StatusInternalServerError,
+			"Internal server error"))
+})
@@ -8,17 +8,23 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/kazimanzurrashid/consents-api-go/logging"
 	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
 )
 
 var _ = Describe("Event", func() {
 	Describe("Create", func() {
 		Context("success", func() {
 			var statusCode int
+			var location string
+			var res models.EventCreateResult
 
 			BeforeEach(func() {
 				var payload bytes.Buffer
@@ -59,6 +65,35 @@ var _ = Describe("Event", func() {
 				handler.ServeHTTP(recorder, req)
 
 				statusCode = recorder.Code
+				location = recorder.Header().Get("Location")
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns created events", func() {
+				Expect(res.Events).To(HaveLen(2))
+				Expect(res.Events[0].ConsentID).To(Equal(models.ConsentEmail))
+				Expect(res.Events[1].ConsentID).To(Equal(models.ConsentSMS))
+			})
+
+			It("returns resulting consent state", func() {
+				Expect(res.Consents).To(HaveLen(2))
+			})
+
+			It("returns changed consents", func() {
+				Expect(res.Changed).To(Equal([]string{
+					models.ConsentEmail,
+					models.ConsentSMS,
+				}))
+			})
+
+			It("returns location of created event", func() {
+				Expect(location).To(Equal(
+					fmt.Sprintf("/v1/events/%v", res.Events[0].ID)))
 			})
 
 			It("returns http status code Created", func() {
@@ -81,6 +116,7 @@ var _ = Describe("Event", func() {
 				}
 
 				recorder := httptest.NewRecorder()
+				recorder.Header().Set(logging.RequestIDHeader, "req-1")
 				event := NewEvent(&fakeEventService{err: nil})
 
 				handler := http.HandlerFunc(event.Create)
@@ -99,6 +135,10 @@ var _ = Describe("Event", func() {
 				Expect(res.Errors[0]).To(MatchRegexp("Malformed request"))
 			})
 
+			It("returns request id", func() {
+				Expect(res.RequestID).To(Equal("req-1"))
+			})
+
 			It("returns http status code UnprocessableEntity", func() {
 				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
 			})
@@ -164,7 +204,7 @@ var _ = Describe("Event", func() {
 			})
 		})
 
-		Context("error in service call", func() {
+		Context("non-existent user", func() {
 			var statusCode int
 			var res errorResult
 
@@ -201,7 +241,127 @@ var _ = Describe("Event", func() {
 				}
 
 				recorder := httptest.NewRecorder()
-				event := NewEvent(&fakeEventService{err: fmt.Errorf("error")})
+				event := NewEvent(&fakeEventService{
+					err: fmt.Errorf("%w: events_users", services.ErrUserNotFound),
+				})
+
+				handler := http.HandlerFunc(event.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns user does not exist in errors", func() {
+				Expect(res.Errors[0]).To(MatchRegexp("User does not exist"))
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("consent not offered by tenant", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				var payload bytes.Buffer
+
+				err := json.NewEncoder(&payload).Encode(models.EventCreateRequest{
+					User: &models.EventCreateUser{
+						ID: "7b5a3155-7a73-42de-b87e-23f50a10180a",
+					},
+					Consents: &[]models.Consent{
+						{
+							ID:      models.ConsentSMS,
+							Enabled: true,
+						},
+					},
+				})
+
+				if err != nil {
+					panic(err)
+				}
+
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/events",
+					&payload)
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				event := NewEvent(&fakeEventService{
+					err: fmt.Errorf(
+						"%w: events_tenant_consents",
+						services.ErrConsentNotOffered),
+				})
+
+				handler := http.HandlerFunc(event.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns consent is not offered in errors", func() {
+				Expect(res.Errors[0]).To(Equal("Consent is not offered"))
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("primary phone missing", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				var payload bytes.Buffer
+
+				err := json.NewEncoder(&payload).Encode(models.EventCreateRequest{
+					User: &models.EventCreateUser{
+						ID: "7b5a3155-7a73-42de-b87e-23f50a10180a",
+					},
+					Consents: &[]models.Consent{
+						{
+							ID:      models.ConsentSMS,
+							Enabled: true,
+						},
+					},
+				})
+
+				if err != nil {
+					panic(err)
+				}
+
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/events",
+					&payload)
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				event := NewEvent(&fakeEventService{
+					err: services.ErrPhoneRequired,
+				})
 
 				handler := http.HandlerFunc(event.Create)
 				handler.ServeHTTP(recorder, req)
@@ -215,23 +375,495 @@ var _ = Describe("Event", func() {
 				}
 			})
 
-			It("returns invalid request in errors", func() {
-				Expect(res.Errors[0]).To(MatchRegexp("Invalid request"))
+			It("returns phone required in errors", func() {
+				Expect(res.Errors[0]).To(Equal("Primary phone number is required"))
 			})
 
 			It("returns http status code UnprocessableEntity", func() {
 				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
 			})
 		})
+
+		Context("nothing changed", func() {
+			var statusCode int
+			var location string
+			var skipUnchanged bool
+			var res models.EventCreateResult
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/events",
+					strings.NewReader(`{
+						"user": {"id": "7b5a3155-7a73-42de-b87e-23f50a10180a"},
+						"consents": [{"id": "email_notifications", "enabled": true}],
+						"skip_unchanged": true
+					}`))
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				srv := &fakeEventService{unchanged: true}
+				event := NewEvent(srv)
+
+				handler := http.HandlerFunc(event.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+				location = recorder.Header().Get("Location")
+				skipUnchanged = srv.created.SkipUnchanged
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("passes skip unchanged to service", func() {
+				Expect(skipUnchanged).To(BeTrue())
+			})
+
+			It("returns no events", func() {
+				Expect(res.Events).To(BeEmpty())
+				Expect(res.Changed).To(BeEmpty())
+				Expect(location).To(BeEmpty())
+			})
+
+			It("returns http status code OK", func() {
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("stale precondition", func() {
+			var statusCode int
+			var ifMatch []string
+			var res errorResult
+
+			BeforeEach(func() {
+				var payload bytes.Buffer
+
+				err := json.NewEncoder(&payload).Encode(models.EventCreateRequest{
+					User: &models.EventCreateUser{
+						ID: "7b5a3155-7a73-42de-b87e-23f50a10180a",
+					},
+					Consents: &[]models.Consent{
+						{
+							ID:      models.ConsentEmail,
+							Enabled: true,
+						},
+						{
+							ID:      models.ConsentSMS,
+							Enabled: false,
+						},
+					},
+				})
+
+				if err != nil {
+					panic(err)
+				}
+
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/events",
+					&payload)
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				req.Header.Set("If-Match", `"abc", W/"def"`)
+				srv := &fakeEventService{err: services.ErrPreconditionFailed}
+				event := NewEvent(srv)
+
+				handler := http.HandlerFunc(event.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+				ifMatch = srv.created.IfMatch
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("passes precondition to service", func() {
+				Expect(ifMatch).To(Equal([]string{"abc", `W/"def"`}))
+			})
+
+			It("returns http status code PreconditionFailed", func() {
+				Expect(statusCode).To(Equal(http.StatusPreconditionFailed))
+			})
+		})
+
+		Context("error in service call", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				var payload bytes.Buffer
+
+				err := json.NewEncoder(&payload).Encode(models.EventCreateRequest{
+					User: &models.EventCreateUser{
+						ID: "7b5a3155-7a73-42de-b87e-23f50a10180a",
+					},
+					Consents: &[]models.Consent{
+						{
+							ID:      models.ConsentEmail,
+							Enabled: true,
+						},
+						{
+							ID:      models.ConsentSMS,
+							Enabled: false,
+						},
+					},
+				})
+
+				if err != nil {
+					panic(err)
+				}
+
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/events",
+					&payload)
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				event := NewEvent(&fakeEventService{err: fmt.Errorf("error")})
+
+				handler := http.HandlerFunc(event.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns internal server error in errors", func() {
+				Expect(res.Errors[0]).To(MatchRegexp("Internal server error"))
+			})
+
+			It("returns http status code InternalServerError", func() {
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("Detail", func() {
+		const id = "0d5f6c8e-3b0a-4c55-9f0e-8a7d3f1b2c4d"
+
+		var (
+			srv      *fakeEventService
+			recorder *httptest.ResponseRecorder
+		)
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("/v1/events/%v", id),
+				nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+
+			recorder = httptest.NewRecorder()
+			http.HandlerFunc(NewEvent(srv).Detail).ServeHTTP(recorder, req)
+		})
+
+		Context("existent", func() {
+			var res models.Event
+
+			BeforeEach(func() {
+				srv = &fakeEventService{event: &models.Event{
+					ID:        id,
+					UserID:    "7b5a3155-7a73-42de-b87e-23f50a10180a",
+					ConsentID: models.ConsentEmail,
+					Enabled:   true,
+					CreatedAt: time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC),
+				}}
+			})
+
+			JustBeforeEach(func() {
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns matching event", func() {
+				Expect(res).To(Equal(*srv.event))
+			})
+
+			It("returns http status code Ok", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("non-existent", func() {
+			BeforeEach(func() {
+				srv = &fakeEventService{err: services.ErrNotFound}
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Chain", func() {
+		const userID = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+		var (
+			srv      *fakeEventService
+			recorder *httptest.ResponseRecorder
+		)
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("/v1/users/%v/chain", userID),
+				nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": userID})
+
+			recorder = httptest.NewRecorder()
+			http.HandlerFunc(NewEvent(srv).Chain).ServeHTTP(recorder, req)
+		})
+
+		Context("broken chain", func() {
+			var res models.ChainVerification
+
+			BeforeEach(func() {
+				srv = &fakeEventService{chain: &models.ChainVerification{
+					UserID: userID,
+					Events: 3,
+					BrokenAt: &models.ChainBreak{
+						EventID:  "0d5f6c8e-3b0a-4c55-9f0e-8a7d3f1b2c4d",
+						Sequence: 2,
+						Reason:   services.ChainHashMismatch,
+					},
+				}}
+			})
+
+			JustBeforeEach(func() {
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns first broken link", func() {
+				Expect(res).To(Equal(*srv.chain))
+			})
+
+			It("returns http status code Ok", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv = &fakeEventService{err: services.ErrNotFound}
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Chains", func() {
+		var (
+			srv      *fakeEventService
+			recorder *httptest.ResponseRecorder
+		)
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(http.MethodGet, "/v1/events/chain", nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			recorder = httptest.NewRecorder()
+			http.HandlerFunc(NewEvent(srv).Chains).ServeHTTP(recorder, req)
+		})
+
+		Context("success", func() {
+			var res models.ChainReport
+
+			BeforeEach(func() {
+				srv = &fakeEventService{report: &models.ChainReport{
+					Users:  2,
+					Events: 5,
+					Valid:  true,
+				}}
+			})
+
+			JustBeforeEach(func() {
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns report", func() {
+				Expect(res).To(Equal(*srv.report))
+			})
+		})
+
+		Context("error in service call", func() {
+			BeforeEach(func() {
+				srv = &fakeEventService{err: fmt.Errorf("db error")}
+			})
+
+			It("returns http status code InternalServerError", func() {
+				Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
 	})
 })
 
 type fakeEventService struct {
-	err error
+	err         error
+	event       *models.Event
+	state       *models.ConsentState
+	states      []models.ConsentState
+	stateErr    error
+	unchanged   bool
+	missingUser string
+	batchErr    error
+	created     *models.EventCreateRequest
+	chain       *models.ChainVerification
+	report      *models.ChainReport
 }
 
 func (srv *fakeEventService) Create(
 	_ context.Context,
-	_ *models.EventCreateRequest) error {
-	return srv.err
+	request *models.EventCreateRequest) (*models.EventCreateResult, error) {
+	srv.created = request
+
+	if srv.err != nil {
+		return nil, srv.err
+	}
+
+	userID := "5c2b8f0e-6a1d-4f3e-9b7c-2d4e6f8a0b1c"
+
+	if request.User != nil {
+		userID = request.User.ID
+	}
+
+	if userID == srv.missingUser {
+		return nil, services.ErrUserNotFound
+	}
+
+	res := &models.EventCreateResult{
+		Events:   make([]models.Event, 0),
+		Changed:  make([]string, 0),
+		Consents: make([]models.Consent, 0),
+		Version:  "abc",
+	}
+
+	for _, consent := range *request.Consents {
+		res.Consents = append(res.Consents, consent)
+		res.States = append(res.States, models.ConsentState{
+			ID:        consent.ID,
+			Enabled:   consent.Enabled,
+			ChangedAt: time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC),
+		})
+
+		if srv.unchanged {
+			continue
+		}
+
+		res.Events = append(res.Events, models.Event{
+			ID:         "0d5f6c8e-3b0a-4c55-9f0e-8a7d3f1b2c4d",
+			UserID:     userID,
+			ConsentID:  consent.ID,
+			Enabled:    consent.Enabled,
+			OccurredAt: time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC),
+			CreatedAt:  time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC),
+		})
+		res.Changed = append(res.Changed, consent.ID)
+	}
+
+	if srv.states != nil {
+		res.States = srv.states
+	}
+
+	return res, nil
+}
+
+func (srv *fakeEventService) CreateBatch(
+	ctx context.Context,
+	request *models.EventBatchRequest) ([]models.EventBatchItemResult, error) {
+	if srv.batchErr != nil {
+		return nil, srv.batchErr
+	}
+
+	results := make([]models.EventBatchItemResult, 0, len(request.Items))
+	var rejected error
+
+	for _, item := range request.Items {
+		item := item
+		res, err := srv.Create(ctx, &item)
+
+		if err != nil {
+			results = append(results, models.EventBatchItemResult{Err: err})
+			rejected = services.ErrBatchRejected
+			continue
+		}
+
+		results = append(results, models.EventBatchItemResult{
+			Events:  res.Events,
+			Changed: res.Changed,
+		})
+	}
+
+	if rejected != nil && request.Atomic() {
+		return results, rejected
+	}
+
+	return results, nil
+}
+
+func (srv *fakeEventService) Detail(
+	_ context.Context,
+	_ string) (*models.Event, error) {
+	return srv.event, srv.err
+}
+
+func (srv *fakeEventService) Consent(
+	_ context.Context,
+	_ string,
+	_ string) (*models.ConsentState, error) {
+	return srv.state, srv.stateErr
+}
+
+func (srv *fakeEventService) VerifyChain(
+	_ context.Context,
+	_ string) (*models.ChainVerification, error) {
+	return srv.chain, srv.err
+}
+
+func (srv *fakeEventService) VerifyChains(
+	_ context.Context) (*models.ChainReport, error) {
+	return srv.report, srv.err
 }
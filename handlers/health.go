@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+const healthCheckTimeout = 2 * time.Second
+
+type HealthCheck func(ctx context.Context) error
+
+type healthCheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type healthResult struct {
+	Status    string                       `json:"status"`
+	Checks    map[string]healthCheckResult `json:"checks,omitempty"`
+	Timestamp string                       `json:"timestamp"`
+}
+
+type Health struct {
+	mu       sync.RWMutex
+	checks   map[string]HealthCheck
+	draining atomic.Bool
+}
+
+func NewHealth(srv services.Health) *Health {
+	h := &Health{checks: make(map[string]HealthCheck)}
+
+	h.AddCheck("database", srv.Ping)
+	h.AddCheck("schema", func(ctx context.Context) error {
+		version, err := srv.SchemaVersion(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if version < services.SchemaVersion {
+			return fmt.Errorf(
+				"schema version %d is behind expected %d",
+				version,
+				services.SchemaVersion)
+		}
+
+		return nil
+	})
+
+	return h
+}
+
+func (h *Health) AddCheck(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks[name] = check
+}
+
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *Health) Live(w http.ResponseWriter, _ *http.Request) {
+	writeSuccess(w, http.StatusOK, healthResult{
+		Status:    "ok",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	res := healthResult{
+		Status:    "ok",
+		Checks:    h.run(r.Context()),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	for _, check := range res.Checks {
+		if check.Status != "ok" {
+			res.Status = "unavailable"
+		}
+	}
+
+	if h.draining.Load() {
+		res.Status = "draining"
+	}
+
+	statusCode := http.StatusOK
+
+	if res.Status != "ok" {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writeSuccess(w, statusCode, res)
+}
+
+func (h *Health) run(ctx context.Context) map[string]healthCheckResult {
+	h.mu.RLock()
+	checks := make(map[string]HealthCheck, len(h.checks))
+
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+
+	h.mu.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]healthCheckResult, len(checks))
+	)
+
+	for name, check := range checks {
+		wg.Add(1)
+
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+
+			result := healthCheckResult{
+				Status:     "ok",
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	return results
+}
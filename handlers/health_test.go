@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Health", func() {
+	Describe("Live", func() {
+		var statusCode int
+
+		BeforeEach(func() {
+			req, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			health := NewHealth(&fakeHealthService{
+				pingErr: fmt.Errorf("connection refused"),
+			})
+
+			handler := http.HandlerFunc(health.Live)
+			handler.ServeHTTP(recorder, req)
+
+			statusCode = recorder.Code
+		})
+
+		It("returns http status code Ok regardless of dependencies", func() {
+			Expect(statusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("Ready", func() {
+		var (
+			srv     *fakeHealthService
+			health  *Health
+			drained bool
+
+			statusCode int
+			res        healthResult
+		)
+
+		BeforeEach(func() {
+			srv = &fakeHealthService{version: services.SchemaVersion}
+			drained = false
+		})
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			health = NewHealth(srv)
+
+			if drained {
+				health.Drain()
+			}
+
+			recorder := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(health.Ready)
+			handler.ServeHTTP(recorder, req)
+
+			statusCode = recorder.Code
+
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		Context("all checks pass", func() {
+			It("returns ok status for every check", func() {
+				Expect(res.Status).To(Equal("ok"))
+				Expect(res.Checks).To(HaveKey("database"))
+				Expect(res.Checks).To(HaveKey("schema"))
+				Expect(res.Checks["database"].Status).To(Equal("ok"))
+				Expect(res.Checks["schema"].Status).To(Equal("ok"))
+			})
+
+			It("returns http status code Ok", func() {
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("database unreachable", func() {
+			BeforeEach(func() {
+				srv.pingErr = fmt.Errorf("connection refused")
+			})
+
+			It("returns failing database check", func() {
+				Expect(res.Status).To(Equal("unavailable"))
+				Expect(res.Checks["database"].Status).To(Equal("fail"))
+				Expect(res.Checks["database"].Error).To(Equal("connection refused"))
+			})
+
+			It("returns http status code ServiceUnavailable", func() {
+				Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+
+		Context("schema behind", func() {
+			BeforeEach(func() {
+				srv.version = services.SchemaVersion - 1
+			})
+
+			It("returns failing schema check", func() {
+				Expect(res.Checks["schema"].Status).To(Equal("fail"))
+			})
+
+			It("returns http status code ServiceUnavailable", func() {
+				Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+
+		Context("draining", func() {
+			BeforeEach(func() {
+				drained = true
+			})
+
+			It("returns draining status", func() {
+				Expect(res.Status).To(Equal("draining"))
+			})
+
+			It("returns http status code ServiceUnavailable", func() {
+				Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+	})
+})
+
+type fakeHealthService struct {
+	pingErr error
+	version int
+}
+
+func (srv *fakeHealthService) Ping(_ context.Context) error {
+	return srv.pingErr
+}
+
+func (srv *fakeHealthService) SchemaVersion(_ context.Context) (int, error) {
+	return srv.version, nil
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+type Identifier struct {
+	srv services.User
+}
+
+func NewIdentifier(srv services.User) *Identifier {
+	return &Identifier{srv}
+}
+
+func (h *Identifier) User(w http.ResponseWriter, r *http.Request) {
+	identifier := identifierPath(r)
+
+	if identifier.Validate() != nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	user, err := h.srv.DetailByIdentifier(r.Context(), identifier)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(user.Version))
+	writeSuccess(w, http.StatusOK, versionOf(r).user(user))
+}
+
+func (h *Identifier) Attach(w http.ResponseWriter, r *http.Request) {
+	user := models.EventCreateUser{ID: mux.Vars(r)["id"]}
+
+	if user.Validate() != nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	identifier := identifierPath(r)
+
+	if err := identifier.Validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	created, err := h.srv.AttachIdentifier(r.Context(), user.ID, identifier)
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrConflict) {
+		writeError(
+			w,
+			http.StatusConflict,
+			"Identifier is attached to another user")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	statusCode := http.StatusOK
+
+	if created {
+		statusCode = http.StatusCreated
+	}
+
+	writeSuccess(w, statusCode, identifier)
+}
+
+func (h *Identifier) Detach(w http.ResponseWriter, r *http.Request) {
+	user := models.EventCreateUser{ID: mux.Vars(r)["id"]}
+	identifier := identifierPath(r)
+
+	if user.Validate() != nil || identifier.Validate() != nil {
+		writeError(w, http.StatusNotFound, "Identifier not found")
+		return
+	}
+
+	err := h.srv.DetachIdentifier(r.Context(), user.ID, identifier)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Identifier not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func identifierPath(r *http.Request) models.Identifier {
+	vars := mux.Vars(r)
+
+	return models.Identifier{
+		Namespace: vars["namespace"],
+		ID:        vars["externalId"],
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Identifier", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	var (
+		srv       *fakeUserService
+		namespace string
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func(method string, handle func(*Identifier) http.HandlerFunc) {
+		req, err := http.NewRequest(method, "/", nil)
+
+		if err != nil {
+			panic(err)
+		}
+
+		req = mux.SetURLVars(req, map[string]string{
+			"id":         id,
+			"namespace":  namespace,
+			"externalId": "42",
+		})
+
+		recorder = httptest.NewRecorder()
+		handle(NewIdentifier(srv)).ServeHTTP(recorder, req)
+	}
+
+	BeforeEach(func() {
+		srv = &fakeUserService{
+			user: &models.User{
+				ID:       id,
+				Email:    "user@example.com",
+				Consents: make([]models.Consent, 0),
+				Identifiers: []models.Identifier{
+					{Namespace: "crm", ID: "42"},
+				},
+				Version: "abc",
+			},
+		}
+		namespace = "crm"
+	})
+
+	Describe("User", func() {
+		user := func(h *Identifier) http.HandlerFunc { return h.User }
+
+		Context("existent", func() {
+			var res models.User
+
+			BeforeEach(func() {
+				serve(http.MethodGet, user)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns matching user", func() {
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Identifiers).To(HaveLen(1))
+			})
+
+			It("returns version of consents", func() {
+				Expect(recorder.Header().Get("ETag")).To(Equal(`"abc"`))
+			})
+		})
+
+		Context("non-existent", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+				serve(http.MethodGet, user)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Attach", func() {
+		attach := func(h *Identifier) http.HandlerFunc { return h.Attach }
+
+		Context("new identifier", func() {
+			var res models.Identifier
+
+			BeforeEach(func() {
+				srv.created = true
+				serve(http.MethodPut, attach)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns attached identifier", func() {
+				Expect(res).To(Equal(models.Identifier{Namespace: "crm", ID: "42"}))
+			})
+
+			It("returns http status code Created", func() {
+				Expect(recorder.Code).To(Equal(http.StatusCreated))
+			})
+		})
+
+		Context("already attached", func() {
+			BeforeEach(func() {
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code OK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("attached to another user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrConflict
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code Conflict", func() {
+				Expect(recorder.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrUserNotFound
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("invalid namespace", func() {
+			BeforeEach(func() {
+				namespace = "Not Valid"
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+	})
+
+	Describe("Detach", func() {
+		detach := func(h *Identifier) http.HandlerFunc { return h.Detach }
+
+		Context("attached", func() {
+			BeforeEach(func() {
+				serve(http.MethodDelete, detach)
+			})
+
+			It("returns http status code NoContent", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			})
+		})
+
+		Context("not attached", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+				serve(http.MethodDelete, detach)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
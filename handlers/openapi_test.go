@@ -0,0 +1,830 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/openapi"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("OpenAPI", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	const receiptID = "3f2a9c1e-8d4b-4e6f-a1c2-5b7d9e0f1a2b"
+
+	var (
+		tenant  *fakeTenantService
+		receipt *fakeReceiptService
+	)
+
+	BeforeEach(func() {
+		tenant = &fakeTenantService{}
+		receipt = newFakeReceiptService(receiptID)
+	})
+
+	newRouter := func(
+		user services.User,
+		event services.Event,
+		health services.Health) *mux.Router {
+		router := mux.NewRouter()
+		Register(router, Handlers{
+			User:       NewUser(user),
+			Event:      NewEvent(event),
+			Consent:    NewConsent(event),
+			Identifier: NewIdentifier(user),
+			Phone:      NewPhone(user),
+			Device:     NewDevice(user),
+			Receipt:    NewReceipt(receipt),
+			Tenant:     NewTenant(tenant, true),
+			Health:     NewHealth(health),
+		})
+		RegisterOperational(router, true)
+
+		return router
+	}
+
+	Describe("routes", func() {
+		It("documents every registered route", func() {
+			router := newRouter(
+				&fakeUserService{},
+				&fakeEventService{},
+				&fakeHealthService{})
+
+			err := router.Walk(func(
+				route *mux.Route,
+				_ *mux.Router,
+				_ []*mux.Route) error {
+				template, err := route.GetPathTemplate()
+
+				if err != nil {
+					return nil
+				}
+
+				methods, err := route.GetMethods()
+
+				if err != nil {
+					return nil
+				}
+
+				for _, method := range methods {
+					operation := lookupPointer(
+						specDocument(),
+						pathItemPointer(template)+"/"+strings.ToLower(method))
+
+					Expect(operation).
+						NotTo(BeNil(), "undocumented %s %s", method, template)
+				}
+
+				return nil
+			})
+
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("tenant errors", func() {
+		var recorder *httptest.ResponseRecorder
+
+		serve := func(headers map[string]string) {
+			req, err := http.NewRequest(
+				http.MethodGet,
+				fmt.Sprintf("/v1/users/%v", id),
+				nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			for name, value := range headers {
+				req.Header.Set(name, value)
+			}
+
+			recorder = httptest.NewRecorder()
+			newRouter(
+				&fakeUserService{},
+				&fakeEventService{},
+				&fakeHealthService{}).ServeHTTP(recorder, req)
+
+			var document interface{}
+
+			if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+				panic(err)
+			}
+
+			schema := responseSchema("/v1/users/{id}", "get", recorder.Code)
+
+			Expect(schema).NotTo(BeNil())
+			Expect(schema.Validate(document)).To(Succeed())
+		}
+
+		It("documents invalid api key", func() {
+			tenant.err = services.ErrNotFound
+			serve(map[string]string{APIKeyHeader: "secret"})
+
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("documents api key of another tenant", func() {
+			tenant.id = "acme"
+			serve(map[string]string{
+				APIKeyHeader:   "secret",
+				TenantIDHeader: "globex",
+			})
+
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	DescribeTable("responses",
+		func(
+			method string,
+			path string,
+			body string,
+			user services.User,
+			event services.Event,
+			health services.Health,
+			expectedStatus int) {
+			req, err := http.NewRequest(method, path, strings.NewReader(body))
+
+			if err != nil {
+				panic(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			router := newRouter(user, event, health)
+			router.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(expectedStatus))
+
+			var match mux.RouteMatch
+			Expect(router.Match(req, &match)).To(BeTrue())
+
+			template, err := match.Route.GetPathTemplate()
+
+			if err != nil {
+				panic(err)
+			}
+
+			schema := responseSchema(
+				template,
+				strings.ToLower(method),
+				recorder.Code)
+
+			if schema == nil {
+				Expect(recorder.Body.Len()).To(BeZero())
+				return
+			}
+
+			var document interface{}
+
+			if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+				panic(err)
+			}
+
+			Expect(schema.Validate(document)).To(Succeed())
+		},
+		Entry("create user",
+			http.MethodPost, "/v1/users",
+			encodeJSON(models.UserCreateRequest{Email: "user@example.com"}),
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+				Email:       "user@example.com",
+				Consents:    make([]models.Consent, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusCreated),
+		Entry("create user with consents",
+			http.MethodPost, "/v1/users",
+			encodeJSON(models.UserCreateRequest{
+				Email: "user@example.com",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}),
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+				Email:       "user@example.com",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusCreated),
+		Entry("upsert existing user",
+			http.MethodPut, "/v1/users",
+			encodeJSON(models.UserCreateRequest{
+				Email:      "user@example.com",
+				ExternalID: "crm-42",
+			}),
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+				Email:       "user@example.com",
+				ExternalID:  "crm-42",
+				Consents:    make([]models.Consent, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("upsert new user",
+			http.MethodPut, "/v1/users",
+			encodeJSON(models.UserCreateRequest{Email: "user@example.com"}),
+			&fakeUserService{
+				user: &models.User{
+					ID:          id,
+					Phones:      make([]models.Phone, 0),
+					Identifiers: make([]models.Identifier, 0),
+					Email:       "user@example.com",
+					Consents:    make([]models.Consent, 0),
+				},
+				created: true,
+			},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusCreated),
+		Entry("create user with phone only",
+			http.MethodPost, "/v1/users",
+			encodeJSON(models.UserCreateRequest{Phone: "+14155550100"}),
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Consents:    make([]models.Consent, 0),
+				Phones:      []models.Phone{{Number: "+14155550100", Primary: true}},
+				Identifiers: make([]models.Identifier, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusCreated),
+		Entry("create user with existing email",
+			http.MethodPost, "/v1/users",
+			encodeJSON(models.UserCreateRequest{Email: "user@example.com"}),
+			&fakeUserService{err: services.ErrConflict},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusConflict),
+		Entry("create user with invalid body",
+			http.MethodPost, "/v1/users",
+			encodeJSON(models.UserCreateRequest{Email: "foo"}),
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusUnprocessableEntity),
+		Entry("get user",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v", id),
+			"",
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+				Email:       "user@example.com",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("get non-existent user",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v", id),
+			"",
+			&fakeUserService{err: services.ErrNotFound},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNotFound),
+		Entry("get merged user",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v", id),
+			"",
+			&fakeUserService{
+				err:        services.ErrNotFound,
+				mergedInto: "0a4f1f8e-51b5-4a8a-9a8e-1f4b0d1c2e3f",
+			},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusMovedPermanently),
+		Entry("export user",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v/export", id),
+			"",
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+				Email:       "user@example.com",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("export non-existent user",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v/export", id),
+			"",
+			&fakeUserService{err: services.ErrNotFound},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNotFound),
+		Entry("export user in unsupported format",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v/export?format=pdf", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusBadRequest),
+		Entry("get legal hold",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v/legal-hold", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("place legal hold",
+			http.MethodPut, fmt.Sprintf("/v1/users/%v/legal-hold", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("place legal hold of non-existent user",
+			http.MethodPut, fmt.Sprintf("/v1/users/%v/legal-hold", id),
+			"",
+			&fakeUserService{err: services.ErrNotFound},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNotFound),
+		Entry("release legal hold",
+			http.MethodDelete, fmt.Sprintf("/v1/users/%v/legal-hold", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNoContent),
+		Entry("merge user",
+			http.MethodPost, fmt.Sprintf("/v1/users/%v/merge", id),
+			encodeJSON(models.UserMergeRequest{
+				UserID: "0a4f1f8e-51b5-4a8a-9a8e-1f4b0d1c2e3f",
+			}),
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Email:       "user@example.com",
+				Consents:    make([]models.Consent, 0),
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("merge non-existent user",
+			http.MethodPost, fmt.Sprintf("/v1/users/%v/merge", id),
+			encodeJSON(models.UserMergeRequest{
+				UserID: "0a4f1f8e-51b5-4a8a-9a8e-1f4b0d1c2e3f",
+			}),
+			&fakeUserService{err: services.ErrUserNotFound},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusUnprocessableEntity),
+		Entry("get device",
+			http.MethodGet, "/v1/devices/cookie-4f1c",
+			"",
+			&fakeUserService{device: &models.Device{
+				ID: "cookie-4f1c",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("get non-existent device",
+			http.MethodGet, "/v1/devices/cookie-4f1c",
+			"",
+			&fakeUserService{err: services.ErrNotFound},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNotFound),
+		Entry("identify device",
+			http.MethodPost, "/v1/devices/cookie-4f1c/identify",
+			encodeJSON(models.DeviceIdentifyRequest{UserID: id}),
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Consents:    make([]models.Consent, 0),
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("get user by identifier",
+			http.MethodGet, "/v1/users/by-identifier/crm/42",
+			"",
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Email:       "user@example.com",
+				Consents:    make([]models.Consent, 0),
+				Phones:      make([]models.Phone, 0),
+				Identifiers: []models.Identifier{{Namespace: "crm", ID: "42"}},
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("attach identifier",
+			http.MethodPut, fmt.Sprintf("/v1/users/%v/identifiers/crm/42", id),
+			"",
+			&fakeUserService{created: true},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusCreated),
+		Entry("attach identifier of another user",
+			http.MethodPut, fmt.Sprintf("/v1/users/%v/identifiers/crm/42", id),
+			"",
+			&fakeUserService{err: services.ErrConflict},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusConflict),
+		Entry("detach identifier",
+			http.MethodDelete, fmt.Sprintf("/v1/users/%v/identifiers/crm/42", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNoContent),
+		Entry("get user by phone",
+			http.MethodGet, "/v1/users/by-phone/+14155550100",
+			"",
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Consents:    make([]models.Consent, 0),
+				Phones:      []models.Phone{{Number: "+14155550100", Primary: true}},
+				Identifiers: make([]models.Identifier, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("attach phone",
+			http.MethodPut, fmt.Sprintf("/v1/users/%v/phones/+14155550100", id),
+			encodeJSON(models.PhoneUpdateRequest{Primary: true}),
+			&fakeUserService{created: true},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusCreated),
+		Entry("attach phone of another user",
+			http.MethodPut, fmt.Sprintf("/v1/users/%v/phones/+14155550100", id),
+			encodeJSON(models.PhoneUpdateRequest{}),
+			&fakeUserService{err: services.ErrPhoneNumberTaken},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusConflict),
+		Entry("detach phone",
+			http.MethodDelete, fmt.Sprintf("/v1/users/%v/phones/+14155550100", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNoContent),
+		Entry("delete user",
+			http.MethodDelete, fmt.Sprintf("/v1/users/%v", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusNoContent),
+		Entry("create events",
+			http.MethodPost, "/v1/events",
+			encodeJSON(models.EventCreateRequest{
+				User: &models.EventCreateUser{ID: id},
+				Consents: &[]models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}),
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusCreated),
+		Entry("create events for device",
+			http.MethodPost, "/v1/events",
+			encodeJSON(models.EventCreateRequest{
+				Device: &models.EventCreateDevice{ID: "cookie-4f1c"},
+				Consents: &[]models.Consent{
+					{ID: models.ConsentEmail, Enabled: false},
+				},
+			}),
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusCreated),
+		Entry("create events without changes",
+			http.MethodPost, "/v1/events",
+			encodeJSON(models.EventCreateRequest{
+				User: &models.EventCreateUser{ID: id},
+				Consents: &[]models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+				SkipUnchanged: true,
+			}),
+			&fakeUserService{},
+			&fakeEventService{unchanged: true},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("create events with duplicate consent",
+			http.MethodPost, "/v1/events",
+			encodeJSON(models.EventCreateRequest{
+				User: &models.EventCreateUser{ID: id},
+				Consents: &[]models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+					{ID: models.ConsentEmail, Enabled: false},
+				},
+			}),
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusUnprocessableEntity),
+		Entry("create events for non-existent user",
+			http.MethodPost, "/v1/events",
+			encodeJSON(models.EventCreateRequest{
+				User: &models.EventCreateUser{ID: id},
+				Consents: &[]models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			}),
+			&fakeUserService{},
+			&fakeEventService{err: services.ErrUserNotFound},
+			&fakeHealthService{},
+			http.StatusUnprocessableEntity),
+		Entry("create event batch",
+			http.MethodPost, "/v1/events/batch",
+			encodeJSON(models.EventBatchRequest{
+				Mode: models.BatchBestEffort,
+				Items: []models.EventCreateRequest{
+					{
+						User: &models.EventCreateUser{ID: id},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+					},
+					{User: &models.EventCreateUser{ID: id}},
+				},
+			}),
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusMultiStatus),
+		Entry("create rejected event batch",
+			http.MethodPost, "/v1/events/batch",
+			encodeJSON(models.EventBatchRequest{
+				Items: []models.EventCreateRequest{
+					{
+						User: &models.EventCreateUser{ID: id},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+					},
+				},
+			}),
+			&fakeUserService{},
+			&fakeEventService{missingUser: id},
+			&fakeHealthService{},
+			http.StatusUnprocessableEntity),
+		Entry("create empty event batch",
+			http.MethodPost, "/v1/events/batch",
+			`{"items": []}`,
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusUnprocessableEntity),
+		Entry("get event",
+			http.MethodGet, fmt.Sprintf("/v1/events/%v", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{event: &models.Event{
+				ID:        id,
+				UserID:    id,
+				ConsentID: models.ConsentEmail,
+				Enabled:   true,
+				CreatedAt: time.Now(),
+			}},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("get non-existent event",
+			http.MethodGet, fmt.Sprintf("/v1/events/%v", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{err: services.ErrNotFound},
+			&fakeHealthService{},
+			http.StatusNotFound),
+		Entry("verify user chain",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v/chain", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{chain: &models.ChainVerification{
+				UserID: id,
+				Events: 2,
+				BrokenAt: &models.ChainBreak{
+					EventID:  id,
+					Sequence: 2,
+					Reason:   services.ChainHashMismatch,
+				},
+			}},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("verify chain of non-existent user",
+			http.MethodGet, fmt.Sprintf("/v1/users/%v/chain", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{err: services.ErrNotFound},
+			&fakeHealthService{},
+			http.StatusNotFound),
+		Entry("verify chains",
+			http.MethodGet, "/v1/events/chain",
+			"",
+			&fakeUserService{},
+			&fakeEventService{report: &models.ChainReport{
+				Users:  3,
+				Events: 7,
+				Valid:  true,
+			}},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("get receipt",
+			http.MethodGet, fmt.Sprintf("/v1/receipts/%v", receiptID),
+			"",
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("get non-existent receipt",
+			http.MethodGet, fmt.Sprintf("/v1/receipts/%v", id),
+			"",
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusNotFound),
+		Entry("get receipt keys",
+			http.MethodGet, "/v1/receipts/keys",
+			"",
+			&fakeUserService{},
+			&fakeEventService{},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("get consent",
+			http.MethodGet,
+			fmt.Sprintf("/v1/users/%v/consents/%v", id, models.ConsentEmail),
+			"",
+			&fakeUserService{},
+			&fakeEventService{state: &models.ConsentState{
+				ID:        models.ConsentEmail,
+				Enabled:   true,
+				ChangedAt: time.Now(),
+			}},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("set consent",
+			http.MethodPut,
+			fmt.Sprintf("/v1/users/%v/consents/%v", id, models.ConsentSMS),
+			`{"enabled": false}`,
+			&fakeUserService{},
+			&fakeEventService{state: &models.ConsentState{
+				ID:        models.ConsentSMS,
+				Enabled:   false,
+				ChangedAt: time.Now(),
+			}},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("set consent of non-existent user",
+			http.MethodPut,
+			fmt.Sprintf("/v1/users/%v/consents/%v", id, models.ConsentSMS),
+			`{"enabled": false}`,
+			&fakeUserService{},
+			&fakeEventService{err: services.ErrUserNotFound},
+			&fakeHealthService{},
+			http.StatusNotFound),
+		Entry("get user through deprecated alias",
+			http.MethodGet, fmt.Sprintf("/users/%v", id),
+			"",
+			&fakeUserService{user: &models.User{
+				ID:          id,
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+				Email:       "user@example.com",
+				Consents:    make([]models.Consent, 0),
+			}},
+			&fakeEventService{}, &fakeHealthService{},
+			http.StatusOK),
+		Entry("root",
+			http.MethodGet, "/",
+			"",
+			&fakeUserService{}, &fakeEventService{},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("openapi document",
+			http.MethodGet, "/openapi.json",
+			"",
+			&fakeUserService{}, &fakeEventService{},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("liveness",
+			http.MethodGet, "/healthz",
+			"",
+			&fakeUserService{}, &fakeEventService{},
+			&fakeHealthService{},
+			http.StatusOK),
+		Entry("readiness",
+			http.MethodGet, "/readyz",
+			"",
+			&fakeUserService{}, &fakeEventService{},
+			&fakeHealthService{version: services.SchemaVersion},
+			http.StatusOK),
+		Entry("readiness with failing dependency",
+			http.MethodGet, "/readyz",
+			"",
+			&fakeUserService{}, &fakeEventService{},
+			&fakeHealthService{pingErr: fmt.Errorf("connection refused")},
+			http.StatusServiceUnavailable),
+	)
+})
+
+func responseSchema(path, method string, status int) *jsonschema.Schema {
+	document := specDocument()
+
+	pointer := fmt.Sprintf(
+		"%s/%s/responses/%d",
+		pathItemPointer(path),
+		method,
+		status)
+
+	response := lookupPointer(document, pointer)
+	Expect(response).NotTo(BeNil(), "undocumented response %s", pointer)
+
+	if ref, ok := response.(map[string]interface{})["$ref"].(string); ok {
+		pointer = ref
+	}
+
+	pointer += "/content/application~1json/schema"
+
+	if lookupPointer(document, pointer) == nil {
+		return nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+
+	if err := compiler.AddResource(
+		"openapi.json",
+		bytes.NewReader(openapi.Spec)); err != nil {
+		panic(err)
+	}
+
+	return compiler.MustCompile("openapi.json" + pointer)
+}
+
+func specDocument() interface{} {
+	var document interface{}
+
+	if err := json.Unmarshal(openapi.Spec, &document); err != nil {
+		panic(err)
+	}
+
+	return document
+}
+
+func pathItemPointer(path string) string {
+	pointer := "#/paths/" + escapePointer(path)
+	item, _ := lookupPointer(specDocument(), pointer).(map[string]interface{})
+
+	if ref, ok := item["$ref"].(string); ok {
+		return ref
+	}
+
+	return pointer
+}
+
+func lookupPointer(document interface{}, pointer string) interface{} {
+	current := document
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "#/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[token]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+
+			if err != nil || index >= len(node) {
+				return nil
+			}
+
+			current = node[index]
+		default:
+			return nil
+		}
+
+		if current == nil {
+			return nil
+		}
+	}
+
+	return current
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func encodeJSON(value interface{}) string {
+	res, err := json.Marshal(value)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return string(res)
+}
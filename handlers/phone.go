@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+type Phone struct {
+	srv services.User
+}
+
+func NewPhone(srv services.User) *Phone {
+	return &Phone{srv}
+}
+
+func (h *Phone) User(w http.ResponseWriter, r *http.Request) {
+	phone := models.Phone{Number: mux.Vars(r)["number"]}
+
+	if phone.Validate() != nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	user, err := h.srv.DetailByPhone(r.Context(), phone.Number)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(user.Version))
+	writeSuccess(w, http.StatusOK, versionOf(r).user(user))
+}
+
+func (h *Phone) Attach(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	user := models.EventCreateUser{ID: vars["id"]}
+
+	if user.Validate() != nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	var req models.PhoneUpdateRequest
+
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	phone := models.Phone{Number: vars["number"], Primary: req.Primary}
+
+	if err := phone.Validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	attached, created, err := h.srv.AttachPhone(r.Context(), user.ID, phone)
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrConflict) {
+		writeError(
+			w,
+			http.StatusConflict,
+			"Phone number is attached to another user")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	statusCode := http.StatusOK
+
+	if created {
+		statusCode = http.StatusCreated
+	}
+
+	writeSuccess(w, statusCode, attached)
+}
+
+func (h *Phone) Detach(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	user := models.EventCreateUser{ID: vars["id"]}
+	phone := models.Phone{Number: vars["number"]}
+
+	if user.Validate() != nil || phone.Validate() != nil {
+		writeError(w, http.StatusNotFound, "Phone number not found")
+		return
+	}
+
+	err := h.srv.DetachPhone(r.Context(), user.ID, phone.Number)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Phone number not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
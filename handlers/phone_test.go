@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Phone", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	var (
+		srv    *fakeUserService
+		number string
+		body   string
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func(method string, handle func(*Phone) http.HandlerFunc) {
+		req, err := http.NewRequest(method, "/", strings.NewReader(body))
+
+		if err != nil {
+			panic(err)
+		}
+
+		req = mux.SetURLVars(req, map[string]string{
+			"id":     id,
+			"number": number,
+		})
+
+		recorder = httptest.NewRecorder()
+		handle(NewPhone(srv)).ServeHTTP(recorder, req)
+	}
+
+	BeforeEach(func() {
+		srv = &fakeUserService{
+			user: &models.User{
+				ID:       id,
+				Consents: make([]models.Consent, 0),
+				Phones: []models.Phone{
+					{Number: "+14155550100", Primary: true},
+				},
+				Identifiers: make([]models.Identifier, 0),
+				Version:     "abc",
+			},
+		}
+		number = "+14155550100"
+		body = `{"primary":true}`
+	})
+
+	Describe("User", func() {
+		user := func(h *Phone) http.HandlerFunc { return h.User }
+
+		Context("existent", func() {
+			var res models.User
+
+			BeforeEach(func() {
+				serve(http.MethodGet, user)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns matching user", func() {
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Phones).To(HaveLen(1))
+			})
+
+			It("returns version of consents", func() {
+				Expect(recorder.Header().Get("ETag")).To(Equal(`"abc"`))
+			})
+		})
+
+		Context("invalid number", func() {
+			BeforeEach(func() {
+				number = "555-0100"
+				serve(http.MethodGet, user)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("non-existent", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+				serve(http.MethodGet, user)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Attach", func() {
+		attach := func(h *Phone) http.HandlerFunc { return h.Attach }
+
+		Context("new phone", func() {
+			var res models.Phone
+
+			BeforeEach(func() {
+				srv.created = true
+				serve(http.MethodPut, attach)
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns attached phone", func() {
+				Expect(res).To(Equal(models.Phone{
+					Number:  "+14155550100",
+					Primary: true,
+				}))
+			})
+
+			It("returns http status code Created", func() {
+				Expect(recorder.Code).To(Equal(http.StatusCreated))
+			})
+		})
+
+		Context("already attached", func() {
+			BeforeEach(func() {
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code OK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("attached to another user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrPhoneNumberTaken
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code Conflict", func() {
+				Expect(recorder.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrUserNotFound
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("invalid number", func() {
+			BeforeEach(func() {
+				number = "0044 7911 123456"
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("malformed body", func() {
+			BeforeEach(func() {
+				body = "{"
+				serve(http.MethodPut, attach)
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+	})
+
+	Describe("Detach", func() {
+		detach := func(h *Phone) http.HandlerFunc { return h.Detach }
+
+		Context("attached", func() {
+			BeforeEach(func() {
+				serve(http.MethodDelete, detach)
+			})
+
+			It("returns http status code NoContent", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			})
+		})
+
+		Context("not attached", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+				serve(http.MethodDelete, detach)
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
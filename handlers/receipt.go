@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+type Receipt struct {
+	srv services.Receipt
+}
+
+func NewReceipt(srv services.Receipt) *Receipt {
+	return &Receipt{srv}
+}
+
+func (h *Receipt) Detail(w http.ResponseWriter, r *http.Request) {
+	receipt, err := h.srv.Detail(r.Context(), mux.Vars(r)["id"])
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Receipt not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, receipt)
+}
+
+func (h *Receipt) Keys(w http.ResponseWriter, _ *http.Request) {
+	writeSuccess(w, http.StatusOK, h.srv.Keys())
+}
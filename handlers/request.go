@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+type validatable interface {
+	Validate() error
+}
+
+func readRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	req validatable) bool {
+	_, decodeSpan := tracing.Start(r.Context(), "decode request")
+	err := json.NewDecoder(r.Body).Decode(req)
+	tracing.End(decodeSpan, err)
+
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Malformed request body")
+		return false
+	}
+
+	_, validateSpan := tracing.Start(r.Context(), "validate request")
+	err = req.Validate()
+	tracing.End(validateSpan, err)
+
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return false
+	}
+
+	return true
+}
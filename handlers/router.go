@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/metrics"
+	"github.com/kazimanzurrashid/consents-api-go/openapi"
+)
+
+type Handlers struct {
+	User       *User
+	Event      *Event
+	Consent    *Consent
+	Identifier *Identifier
+	Phone      *Phone
+	Device     *Device
+	Receipt    *Receipt
+	Tenant     *Tenant
+	Health     *Health
+}
+
+func Register(router *mux.Router, h Handlers) {
+	router.HandleFunc("/healthz", h.Health.Live).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.Health.Ready).Methods(http.MethodGet)
+
+	registerVersion(router.PathPrefix("/v1").Subrouter(), v1, h)
+
+	unversioned := router.NewRoute().Subrouter()
+	unversioned.Use(withVersion(v1), deprecated(v1), h.Tenant.Middleware)
+	registerUnversioned(unversioned, h)
+}
+
+func registerVersion(router *mux.Router, version apiVersion, h Handlers) {
+	router.Use(withVersion(version), h.Tenant.Middleware)
+
+	router.HandleFunc("/users", h.User.Create).Methods(http.MethodPost)
+	router.HandleFunc("/users", h.User.Upsert).Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}", h.User.Delete).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id}", h.User.Detail).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/merge", h.User.Merge).
+		Methods(http.MethodPost)
+	router.HandleFunc(
+		"/users/by-identifier/{namespace}/{externalId}",
+		h.Identifier.User).
+		Methods(http.MethodGet)
+	router.HandleFunc(
+		"/users/{id}/identifiers/{namespace}/{externalId}",
+		h.Identifier.Attach).
+		Methods(http.MethodPut)
+	router.HandleFunc(
+		"/users/{id}/identifiers/{namespace}/{externalId}",
+		h.Identifier.Detach).
+		Methods(http.MethodDelete)
+	router.HandleFunc("/users/by-phone/{number}", h.Phone.User).
+		Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/phones/{number}", h.Phone.Attach).
+		Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}/phones/{number}", h.Phone.Detach).
+		Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id}/chain", h.Event.Chain).
+		Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/export", h.User.Export).
+		Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/legal-hold", h.User.LegalHold).
+		Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/legal-hold", h.User.PlaceLegalHold).
+		Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}/legal-hold", h.User.ReleaseLegalHold).
+		Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id}/consents/{consentId}", h.Consent.Detail).
+		Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/consents/{consentId}", h.Consent.Update).
+		Methods(http.MethodPut)
+	router.HandleFunc("/devices/{deviceId}", h.Device.Detail).
+		Methods(http.MethodGet)
+	router.HandleFunc("/devices/{deviceId}/identify", h.Device.Identify).
+		Methods(http.MethodPost)
+	router.HandleFunc("/events", h.Event.Create).Methods(http.MethodPost)
+	router.HandleFunc("/events/batch", h.Event.CreateBatch).
+		Methods(http.MethodPost)
+	router.HandleFunc("/events/chain", h.Event.Chains).
+		Methods(http.MethodGet)
+	router.HandleFunc("/events/{id}", h.Event.Detail).Methods(http.MethodGet)
+	router.HandleFunc("/receipts/keys", h.Receipt.Keys).Methods(http.MethodGet)
+	router.HandleFunc("/receipts/{id}", h.Receipt.Detail).
+		Methods(http.MethodGet)
+}
+
+func registerUnversioned(router *mux.Router, h Handlers) {
+	router.HandleFunc("/users", h.User.Create).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id}", h.User.Delete).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id}", h.User.Detail).Methods(http.MethodGet)
+	router.HandleFunc("/events", h.Event.Create).Methods(http.MethodPost)
+}
+
+func RegisterOperational(router *mux.Router, docs bool) {
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	router.Handle("/openapi.json", openapi.Handler()).Methods(http.MethodGet)
+
+	if docs {
+		router.Handle("/docs", openapi.DocsHandler()).Methods(http.MethodGet)
+	}
+
+	router.HandleFunc("/", root).Methods(http.MethodGet)
+}
+
+func root(w http.ResponseWriter, _ *http.Request) {
+	writeSuccess(w, http.StatusOK, struct {
+		Result    string `json:"result"`
+		Timestamp string `json:"timestamp"`
+	}{
+		Result:    "ok",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("Register", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	var recorder *httptest.ResponseRecorder
+
+	serve := func(path string) {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+
+		if err != nil {
+			panic(err)
+		}
+
+		router := mux.NewRouter()
+		Register(router, Handlers{
+			User: NewUser(&fakeUserService{user: &models.User{
+				ID:       id,
+				Email:    "user@example.com",
+				Consents: make([]models.Consent, 0),
+			}}),
+			Event:      NewEvent(&fakeEventService{}),
+			Consent:    NewConsent(&fakeEventService{}),
+			Identifier: NewIdentifier(&fakeUserService{}),
+			Phone:      NewPhone(&fakeUserService{}),
+			Device:     NewDevice(&fakeUserService{}),
+			Receipt:    NewReceipt(&fakeReceiptService{}),
+			Tenant:     NewTenant(&fakeTenantService{}, true),
+			Health:     NewHealth(&fakeHealthService{}),
+		})
+
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+	}
+
+	Context("versioned path", func() {
+		BeforeEach(func() {
+			serve(fmt.Sprintf("/v1/users/%v", id))
+		})
+
+		It("returns http status code Ok", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+
+		It("does not return deprecation headers", func() {
+			Expect(recorder.Header().Get("Deprecation")).To(BeEmpty())
+			Expect(recorder.Header().Get("Sunset")).To(BeEmpty())
+		})
+	})
+
+	Context("unversioned path", func() {
+		BeforeEach(func() {
+			serve(fmt.Sprintf("/users/%v", id))
+		})
+
+		It("returns http status code Ok", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+
+		It("returns deprecation headers", func() {
+			Expect(recorder.Header().Get("Deprecation")).To(MatchRegexp(`^@\d+$`))
+			Expect(recorder.Header().Get("Sunset")).To(HaveSuffix("GMT"))
+		})
+
+		It("links to successor version", func() {
+			Expect(recorder.Header().Get("Link")).To(Equal(
+				fmt.Sprintf(`</v1/users/%v>; rel="successor-version"`, id)))
+		})
+	})
+
+	Context("operational path", func() {
+		BeforeEach(func() {
+			serve("/healthz")
+		})
+
+		It("is not versioned", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Deprecation")).To(BeEmpty())
+		})
+	})
+})
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"io"
+	"log/slog"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -8,10 +10,13 @@ import (
 )
 
 type errorResult struct {
-	Errors []string `json:"errors"`
+	Errors    []string `json:"errors"`
+	RequestID string   `json:"request_id"`
 }
 
 func Test(t *testing.T) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Handlers Suite")
 }
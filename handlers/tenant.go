@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+const (
+	APIKeyHeader   = "X-API-Key"
+	TenantIDHeader = "X-Tenant-ID"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]{0,63}$`)
+
+type Tenant struct {
+	srv         services.Tenant
+	trustHeader bool
+}
+
+func NewTenant(srv services.Tenant, trustHeader bool) *Tenant {
+	return &Tenant{srv, trustHeader}
+}
+
+func (h *Tenant) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		tenantID := r.Header.Get(TenantIDHeader)
+
+		switch {
+		case key != "":
+			owner, err := h.srv.ByAPIKey(r.Context(), key)
+
+			if errors.Is(err, services.ErrNotFound) {
+				writeError(w, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+
+			if err != nil {
+				writeServerError(w, r, err)
+				return
+			}
+
+			if tenantID != "" && tenantID != owner {
+				writeError(
+					w,
+					http.StatusForbidden,
+					"API key does not belong to tenant")
+				return
+			}
+
+			tenantID = owner
+		case tenantID != "" && !h.trustHeader:
+			writeError(w, http.StatusUnauthorized, "API key required")
+			return
+		case tenantID != "":
+			if !tenantIDPattern.MatchString(tenantID) {
+				writeError(w, http.StatusUnauthorized, "Unknown tenant")
+				return
+			}
+
+			err := h.srv.Exists(r.Context(), tenantID)
+
+			if errors.Is(err, services.ErrNotFound) {
+				writeError(w, http.StatusUnauthorized, "Unknown tenant")
+				return
+			}
+
+			if err != nil {
+				writeServerError(w, r, err)
+				return
+			}
+		default:
+			tenantID = services.DefaultTenant
+		}
+
+		next.ServeHTTP(
+			w,
+			r.WithContext(services.WithTenant(r.Context(), tenantID)))
+	})
+}
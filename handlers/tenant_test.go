@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("Tenant", func() {
+	var (
+		srv         *fakeTenantService
+		headers     map[string]string
+		trustHeader bool
+
+		tenantID string
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func() {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+
+		if err != nil {
+			panic(err)
+		}
+
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		tenantID = ""
+		recorder = httptest.NewRecorder()
+
+		NewTenant(srv, trustHeader).Middleware(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				tenantID = services.TenantID(r.Context())
+				w.WriteHeader(http.StatusNoContent)
+			})).ServeHTTP(recorder, req)
+	}
+
+	BeforeEach(func() {
+		srv = &fakeTenantService{id: "acme"}
+		headers = map[string]string{}
+		trustHeader = true
+	})
+
+	Context("no credential", func() {
+		BeforeEach(func() {
+			serve()
+		})
+
+		It("uses default tenant", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			Expect(tenantID).To(Equal(services.DefaultTenant))
+		})
+	})
+
+	Context("api key", func() {
+		BeforeEach(func() {
+			headers[APIKeyHeader] = "secret"
+			serve()
+		})
+
+		It("uses tenant of api key", func() {
+			Expect(tenantID).To(Equal("acme"))
+		})
+	})
+
+	Context("invalid api key", func() {
+		BeforeEach(func() {
+			headers[APIKeyHeader] = "secret"
+			srv.err = services.ErrNotFound
+			serve()
+		})
+
+		It("returns http status code Unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(tenantID).To(BeEmpty())
+		})
+	})
+
+	Context("api key of another tenant", func() {
+		BeforeEach(func() {
+			headers[APIKeyHeader] = "secret"
+			headers[TenantIDHeader] = "globex"
+			serve()
+		})
+
+		It("returns http status code Forbidden", func() {
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("tenant header", func() {
+		BeforeEach(func() {
+			headers[TenantIDHeader] = "globex"
+			serve()
+		})
+
+		It("uses tenant of header", func() {
+			Expect(tenantID).To(Equal("globex"))
+		})
+	})
+
+	Context("tenant header not trusted", func() {
+		BeforeEach(func() {
+			headers[TenantIDHeader] = "globex"
+			trustHeader = false
+			serve()
+		})
+
+		It("returns http status code Unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(tenantID).To(BeEmpty())
+		})
+	})
+
+	Context("tenant header not trusted with api key", func() {
+		BeforeEach(func() {
+			headers[APIKeyHeader] = "secret"
+			headers[TenantIDHeader] = "acme"
+			trustHeader = false
+			serve()
+		})
+
+		It("uses tenant of api key", func() {
+			Expect(tenantID).To(Equal("acme"))
+		})
+	})
+
+	Context("unknown tenant header", func() {
+		BeforeEach(func() {
+			headers[TenantIDHeader] = "globex"
+			srv.err = services.ErrNotFound
+			serve()
+		})
+
+		It("returns http status code Unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("invalid tenant header", func() {
+		BeforeEach(func() {
+			headers[TenantIDHeader] = "Not Valid"
+			serve()
+		})
+
+		It("returns http status code Unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
+
+type fakeTenantService struct {
+	id  string
+	err error
+}
+
+func (srv *fakeTenantService) ByAPIKey(_ context.Context, _ string) (string, error) {
+	return srv.id, srv.err
+}
+
+func (srv *fakeTenantService) Exists(_ context.Context, _ string) error {
+	return srv.err
+}
+
+func (srv *fakeTenantService) List(_ context.Context) ([]string, error) {
+	return []string{srv.id}, srv.err
+}
@@ -1,8 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"path"
 
 	"github.com/gorilla/mux"
 
@@ -21,31 +22,92 @@ func NewUser(srv services.User) *User {
 func (h *User) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.UserCreateRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusUnprocessableEntity, "Malformed request body")
+	if !readRequest(w, r, &req) {
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	user, err := h.srv.Create(r.Context(), &req)
+
+	if errors.Is(err, services.ErrConsentNotOffered) {
+		writeError(w, http.StatusUnprocessableEntity, "Consent is not offered")
 		return
 	}
 
-	user, err := h.srv.Create(r.Context(), &req)
+	if errors.Is(err, services.ErrPhoneRequired) {
+		writeError(w, http.StatusUnprocessableEntity, "Primary phone number is required")
+		return
+	}
+
+	if errors.Is(err, services.ErrConflict) {
+		writeError(w, http.StatusConflict, conflictMessage(err))
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(user.Version))
+	writeSuccess(w, http.StatusCreated, versionOf(r).user(user))
+}
+
+func (h *User) Upsert(w http.ResponseWriter, r *http.Request) {
+	var req models.UserCreateRequest
+
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	user, created, err := h.srv.Upsert(r.Context(), &req)
+
+	if errors.Is(err, services.ErrConsentNotOffered) {
+		writeError(w, http.StatusUnprocessableEntity, "Consent is not offered")
+		return
+	}
+
+	if errors.Is(err, services.ErrPhoneRequired) {
+		writeError(w, http.StatusUnprocessableEntity, "Primary phone number is required")
+		return
+	}
+
+	if errors.Is(err, services.ErrConflict) {
+		writeError(w, http.StatusConflict, conflictMessage(err))
+		return
+	}
 
 	if err != nil {
-		writeError(w, http.StatusUnprocessableEntity, "Email already exists")
+		writeServerError(w, r, err)
 		return
 	}
 
-	writeSuccess(w, http.StatusCreated, user)
+	statusCode := http.StatusOK
+
+	if created {
+		statusCode = http.StatusCreated
+	}
+
+	w.Header().Set("ETag", formatETag(user.Version))
+	writeSuccess(w, statusCode, versionOf(r).user(user))
 }
 
 func (h *User) Delete(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	if err := h.srv.Delete(r.Context(), id); err != nil {
-		writeError(w, http.StatusInternalServerError, "Internal server error")
+	err := h.srv.Delete(r.Context(), id)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrLegalHold) {
+		writeError(w, http.StatusConflict, "User is under legal hold")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
 		return
 	}
 
@@ -57,15 +119,101 @@ func (h *User) Detail(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.srv.Detail(r.Context(), id)
 
+	if errors.Is(err, services.ErrNotFound) {
+		h.redirectMerged(w, r, id)
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(user.Version))
+
+	if ifNoneMatch(r, user.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, versionOf(r).user(user))
+}
+
+func (h *User) Merge(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req models.UserMergeRequest
+
+	if !readRequest(w, r, &req) {
+		return
+	}
+
+	user, err := h.srv.Merge(r.Context(), id, &req)
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(
+			w,
+			http.StatusUnprocessableEntity,
+			"User to merge does not exist")
+		return
+	}
+
+	if errors.Is(err, services.ErrSameUser) {
+		writeError(
+			w,
+			http.StatusUnprocessableEntity,
+			"User cannot be merged into itself")
+		return
+	}
+
+	if errors.Is(err, services.ErrChainBroken) {
+		writeError(w, http.StatusConflict, "Event chain is broken")
+		return
+	}
+
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Internal server error")
+		writeServerError(w, r, err)
 		return
 	}
 
-	if user == nil {
+	w.Header().Set("ETag", formatETag(user.Version))
+	writeSuccess(w, http.StatusOK, versionOf(r).user(user))
+}
+
+func (h *User) redirectMerged(
+	w http.ResponseWriter,
+	r *http.Request,
+	id string) {
+	target, err := h.srv.MergedInto(r.Context(), id)
+
+	if errors.Is(err, services.ErrNotFound) {
 		writeError(w, http.StatusNotFound, "User not found")
 		return
 	}
 
-	writeSuccess(w, http.StatusOK, user)
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(path.Dir(r.URL.Path), target))
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+func conflictMessage(err error) string {
+	switch {
+	case errors.Is(err, services.ErrPhoneNumberTaken):
+		return "Phone number already exists"
+	case errors.Is(err, services.ErrExternalIDTaken):
+		return "External id already exists"
+	case errors.Is(err, services.ErrEmailTaken):
+		return "Email already exists"
+	default:
+		return "User already exists"
+	}
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/export"
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+func (h *User) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		format = models.ExportJSON
+	}
+
+	if format != models.ExportJSON && format != models.ExportZip {
+		writeError(w, http.StatusBadRequest, "Unsupported export format")
+		return
+	}
+
+	bundle, err := h.srv.Export(r.Context(), mux.Vars(r)["id"])
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	var content bytes.Buffer
+
+	if err := export.Write(&content, bundle, format); err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(
+			"attachment; filename=%q",
+			export.Filename(bundle.User.ID, format)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content.Bytes())
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("User", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	Describe("Export", func() {
+		var (
+			srv      *fakeUserService
+			format   string
+			recorder *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			srv = &fakeUserService{user: &models.User{
+				ID:          id,
+				Email:       "user@example.com",
+				Consents:    make([]models.Consent, 0),
+				Phones:      make([]models.Phone, 0),
+				Identifiers: make([]models.Identifier, 0),
+			}}
+			format = ""
+		})
+
+		JustBeforeEach(func() {
+			path := fmt.Sprintf("/v1/users/%v/export", id)
+
+			if format != "" {
+				path += "?format=" + format
+			}
+
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+
+			recorder = httptest.NewRecorder()
+			http.HandlerFunc(NewUser(srv).Export).ServeHTTP(recorder, req)
+		})
+
+		Context("json", func() {
+			It("returns bundle as attachment", func() {
+				var res models.UserExport
+
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Header().Get("Content-Disposition")).
+					To(Equal(`attachment; filename="user-` + id + `.json"`))
+				Expect(json.NewDecoder(recorder.Body).Decode(&res)).To(Succeed())
+				Expect(res.User.Email).To(Equal("user@example.com"))
+			})
+		})
+
+		Context("zip", func() {
+			BeforeEach(func() {
+				format = models.ExportZip
+			})
+
+			It("returns archive with json and html summary", func() {
+				archive, err := zip.NewReader(
+					bytes.NewReader(recorder.Body.Bytes()),
+					int64(recorder.Body.Len()))
+
+				Expect(err).To(BeNil())
+				Expect(recorder.Header().Get("Content-Type")).To(Equal("application/zip"))
+				Expect(archive.File).To(HaveLen(2))
+			})
+		})
+
+		Context("unsupported format", func() {
+			BeforeEach(func() {
+				format = "xml"
+			})
+
+			It("returns http status code BadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
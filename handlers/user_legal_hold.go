@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+func (h *User) LegalHold(w http.ResponseWriter, r *http.Request) {
+	hold, err := h.srv.LegalHold(r.Context(), mux.Vars(r)["id"])
+
+	h.writeLegalHold(w, r, hold, err)
+}
+
+func (h *User) PlaceLegalHold(w http.ResponseWriter, r *http.Request) {
+	hold, err := h.srv.PlaceLegalHold(r.Context(), mux.Vars(r)["id"])
+
+	h.writeLegalHold(w, r, hold, err)
+}
+
+func (h *User) ReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	err := h.srv.ReleaseLegalHold(r.Context(), mux.Vars(r)["id"])
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *User) writeLegalHold(
+	w http.ResponseWriter,
+	r *http.Request,
+	hold *models.LegalHold,
+	err error) {
+
+	if errors.Is(err, services.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	writeSuccess(w, http.StatusOK, hold)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("User", func() {
+	const id = "7b5a3155-7a73-42de-b87e-23f50a10180a"
+
+	var (
+		srv      *fakeUserService
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func(method string, handle func(*User) http.HandlerFunc) {
+		req, err := http.NewRequest(method, "/", nil)
+
+		if err != nil {
+			panic(err)
+		}
+
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+
+		recorder = httptest.NewRecorder()
+		handle(NewUser(srv)).ServeHTTP(recorder, req)
+	}
+
+	BeforeEach(func() {
+		srv = &fakeUserService{}
+	})
+
+	Describe("LegalHold", func() {
+		Context("held", func() {
+			var placedAt time.Time
+
+			BeforeEach(func() {
+				placedAt = time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+				srv.hold = &models.LegalHold{
+					UserID:   id,
+					Held:     true,
+					PlacedAt: &placedAt,
+				}
+
+				serve(http.MethodGet, func(h *User) http.HandlerFunc {
+					return h.LegalHold
+				})
+			})
+
+			It("returns http status code OK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+
+			It("returns hold", func() {
+				var res models.LegalHold
+
+				_ = json.NewDecoder(recorder.Body).Decode(&res)
+
+				Expect(res.Held).To(BeTrue())
+				Expect(res.PlacedAt.Equal(placedAt)).To(BeTrue())
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+
+				serve(http.MethodGet, func(h *User) http.HandlerFunc {
+					return h.LegalHold
+				})
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("PlaceLegalHold", func() {
+		Context("existent user", func() {
+			BeforeEach(func() {
+				serve(http.MethodPut, func(h *User) http.HandlerFunc {
+					return h.PlaceLegalHold
+				})
+			})
+
+			It("returns placed hold", func() {
+				var res models.LegalHold
+
+				_ = json.NewDecoder(recorder.Body).Decode(&res)
+
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(res.UserID).To(Equal(id))
+				Expect(res.Held).To(BeTrue())
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+
+				serve(http.MethodPut, func(h *User) http.HandlerFunc {
+					return h.PlaceLegalHold
+				})
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("ReleaseLegalHold", func() {
+		Context("existent user", func() {
+			BeforeEach(func() {
+				serve(http.MethodDelete, func(h *User) http.HandlerFunc {
+					return h.ReleaseLegalHold
+				})
+			})
+
+			It("returns http status code NoContent", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrNotFound
+
+				serve(http.MethodDelete, func(h *User) http.HandlerFunc {
+					return h.ReleaseLegalHold
+				})
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("server error", func() {
+			BeforeEach(func() {
+				srv.err = errors.New("connection reset")
+
+				serve(http.MethodDelete, func(h *User) http.HandlerFunc {
+					return h.ReleaseLegalHold
+				})
+			})
+
+			It("returns http status code InternalServerError", func() {
+				Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+})
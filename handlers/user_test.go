@@ -8,12 +8,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
 )
 
 var _ = Describe("User", func() {
@@ -78,6 +81,96 @@ var _ = Describe("User", func() {
 			})
 		})
 
+		Context("with initial consents", func() {
+			var statusCode int
+			var etag string
+			var res models.User
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/users",
+					strings.NewReader(`{
+						"email": "user@example.com",
+						"consents": [{"id": "sms_notifications", "enabled": true}]
+					}`))
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{
+					user: &models.User{
+						ID:    id,
+						Email: email,
+						Consents: []models.Consent{
+							{ID: models.ConsentSMS, Enabled: true},
+						},
+						Version: "abc",
+					},
+				})
+
+				handler := http.HandlerFunc(user.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+				etag = recorder.Header().Get("ETag")
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns user with consents", func() {
+				Expect(res.Consents).To(HaveLen(1))
+				Expect(res.Consents[0].ID).To(Equal(models.ConsentSMS))
+			})
+
+			It("returns version of consents", func() {
+				Expect(etag).To(Equal(`"abc"`))
+			})
+
+			It("returns http status code Created", func() {
+				Expect(statusCode).To(Equal(http.StatusCreated))
+			})
+		})
+
+		Context("with duplicate consents", func() {
+			var statusCode int
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/users",
+					strings.NewReader(`{
+						"email": "user@example.com",
+						"consents": [
+							{"id": "sms_notifications", "enabled": true},
+							{"id": "sms_notifications", "enabled": false}
+						]
+					}`))
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{})
+
+				handler := http.HandlerFunc(user.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
 		Context("error reading request body", func() {
 			var statusCode int
 			var res errorResult
@@ -164,7 +257,7 @@ var _ = Describe("User", func() {
 			})
 		})
 
-		Context("error in service call", func() {
+		Context("email already exists", func() {
 			var statusCode int
 			var res errorResult
 
@@ -189,7 +282,9 @@ var _ = Describe("User", func() {
 				}
 
 				recorder := httptest.NewRecorder()
-				user := NewUser(&fakeUserService{err: fmt.Errorf("error")})
+				user := NewUser(&fakeUserService{
+					err: fmt.Errorf("%w: uq_email", services.ErrEmailTaken),
+				})
 
 				handler := http.HandlerFunc(user.Create)
 				handler.ServeHTTP(recorder, req)
@@ -207,12 +302,202 @@ var _ = Describe("User", func() {
 				Expect(res.Errors[0]).To(MatchRegexp("Email already exists"))
 			})
 
-			It("returns http status code UnprocessableEntity", func() {
-				Expect(statusCode).To(Equal(http.StatusUnprocessableEntity))
+			It("returns http status code Conflict", func() {
+				Expect(statusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("phone already exists", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				var payload bytes.Buffer
+
+				err := json.NewEncoder(&payload).Encode(models.UserCreateRequest{
+					Phone: "+14155550100",
+				})
+
+				if err != nil {
+					panic(err)
+				}
+
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/users",
+					&payload)
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{
+					err: fmt.Errorf("%w: pk_user_phones", services.ErrPhoneNumberTaken),
+				})
+
+				handler := http.HandlerFunc(user.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns phone exists in errors", func() {
+				Expect(res.Errors[0]).To(MatchRegexp("Phone number already exists"))
+			})
+
+			It("returns http status code Conflict", func() {
+				Expect(statusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("error in service call", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				var payload bytes.Buffer
+
+				err := json.NewEncoder(&payload).Encode(models.UserCreateRequest{
+					Email: "user@example.com",
+				})
+
+				if err != nil {
+					panic(err)
+				}
+
+				req, err := http.NewRequest(
+					http.MethodPost,
+					"/users",
+					&payload)
+
+				if err != nil {
+					panic(err)
+				}
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{err: fmt.Errorf("error")})
+
+				handler := http.HandlerFunc(user.Create)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns internal server error in errors", func() {
+				Expect(res.Errors[0]).To(MatchRegexp("Internal server error"))
+			})
+
+			It("returns http status code InternalServerError", func() {
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
 			})
 		})
 	})
 
+	Describe("Upsert", func() {
+		var (
+			srv        *fakeUserService
+			statusCode int
+			res        models.User
+		)
+
+		BeforeEach(func() {
+			srv = &fakeUserService{
+				user: &models.User{
+					ID:         id,
+					Email:      email,
+					ExternalID: "crm-42",
+					Consents:   make([]models.Consent, 0),
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				http.MethodPut,
+				"/users",
+				strings.NewReader(`{"email": "user@example.com", "external_id": "crm-42"}`))
+
+			if err != nil {
+				panic(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			user := NewUser(srv)
+
+			handler := http.HandlerFunc(user.Upsert)
+			handler.ServeHTTP(recorder, req)
+
+			statusCode = recorder.Code
+			res = models.User{}
+
+			_ = json.NewDecoder(recorder.Body).Decode(&res)
+		})
+
+		Context("new user", func() {
+			BeforeEach(func() {
+				srv.created = true
+			})
+
+			It("returns newly created user", func() {
+				Expect(res.ExternalID).To(Equal("crm-42"))
+			})
+
+			It("returns http status code Created", func() {
+				Expect(statusCode).To(Equal(http.StatusCreated))
+			})
+		})
+
+		Context("existing user", func() {
+			It("returns existing user", func() {
+				Expect(res.ID).To(Equal(id))
+			})
+
+			It("returns http status code OK", func() {
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("email taken by another user", func() {
+			BeforeEach(func() {
+				srv.err = services.ErrConflict
+			})
+
+			It("returns http status code Conflict", func() {
+				Expect(statusCode).To(Equal(http.StatusConflict))
+			})
+		})
+	})
+
+	DescribeTable("conflict message",
+		func(err error, message string) {
+			Expect(conflictMessage(err)).To(Equal(message))
+		},
+		Entry("email",
+			fmt.Errorf("%w: uq_email", services.ErrEmailTaken),
+			"Email already exists"),
+		Entry("external id",
+			fmt.Errorf("%w: uq_external_id", services.ErrExternalIDTaken),
+			"External id already exists"),
+		Entry("phone number",
+			fmt.Errorf("%w: pk_user_phones", services.ErrPhoneNumberTaken),
+			"Phone number already exists"),
+		Entry("other constraint",
+			fmt.Errorf("%w: pk_users", services.ErrConflict),
+			"User already exists"))
+
 	Describe("Delete", func() {
 		Context("success", func() {
 			var statusCode int
@@ -245,6 +530,96 @@ var _ = Describe("User", func() {
 			})
 		})
 
+		Context("non-existent", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodDelete,
+					fmt.Sprintf("/users/%v", id),
+					nil)
+
+				if err != nil {
+					panic(err)
+				}
+
+				req = mux.SetURLVars(req, map[string]string{
+					"id": id,
+				})
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{
+					err:  services.ErrNotFound,
+					user: nil,
+				})
+
+				handler := http.HandlerFunc(user.Delete)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns user not found in errors", func() {
+				Expect(res.Errors[0]).To(MatchRegexp("User not found"))
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(statusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("under legal hold", func() {
+			var statusCode int
+			var res errorResult
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodDelete,
+					fmt.Sprintf("/users/%v", id),
+					nil)
+
+				if err != nil {
+					panic(err)
+				}
+
+				req = mux.SetURLVars(req, map[string]string{
+					"id": id,
+				})
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{
+					err:  services.ErrLegalHold,
+					user: nil,
+				})
+
+				handler := http.HandlerFunc(user.Delete)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+
+				if err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns legal hold in errors", func() {
+				Expect(res.Errors[0]).To(MatchRegexp("User is under legal hold"))
+			})
+
+			It("returns http status code Conflict", func() {
+				Expect(statusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
 		Context("error in service call", func() {
 			var statusCode int
 			var res errorResult
@@ -294,6 +669,7 @@ var _ = Describe("User", func() {
 	Describe("Detail", func() {
 		Context("existent", func() {
 			var statusCode int
+			var etag string
 			var res models.User
 
 			BeforeEach(func() {
@@ -313,8 +689,9 @@ var _ = Describe("User", func() {
 				recorder := httptest.NewRecorder()
 				user := NewUser(&fakeUserService{
 					user: &models.User{
-						ID:    id,
-						Email: email,
+						ID:      id,
+						Email:   email,
+						Version: "abc",
 						Consents: []models.Consent{
 							{
 								ID:      models.ConsentEmail,
@@ -333,6 +710,7 @@ var _ = Describe("User", func() {
 				handler.ServeHTTP(recorder, req)
 
 				statusCode = recorder.Code
+				etag = recorder.Header().Get("ETag")
 
 				err = json.NewDecoder(recorder.Body).Decode(&res)
 
@@ -353,6 +731,49 @@ var _ = Describe("User", func() {
 			It("returns http status code Ok", func() {
 				Expect(statusCode).To(Equal(http.StatusOK))
 			})
+
+			It("returns version as etag", func() {
+				Expect(etag).To(Equal(`"abc"`))
+			})
+		})
+
+		Context("not modified", func() {
+			var statusCode int
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodGet,
+					fmt.Sprintf("/users/%v", id),
+					nil)
+
+				if err != nil {
+					panic(err)
+				}
+
+				req.Header.Set("If-None-Match", `"abc"`)
+				req = mux.SetURLVars(req, map[string]string{
+					"id": id,
+				})
+
+				recorder := httptest.NewRecorder()
+				user := NewUser(&fakeUserService{
+					user: &models.User{
+						ID:       id,
+						Email:    email,
+						Consents: make([]models.Consent, 0),
+						Version:  "abc",
+					},
+				})
+
+				handler := http.HandlerFunc(user.Detail)
+				handler.ServeHTTP(recorder, req)
+
+				statusCode = recorder.Code
+			})
+
+			It("returns http status code NotModified", func() {
+				Expect(statusCode).To(Equal(http.StatusNotModified))
+			})
 		})
 
 		Context("non-existent", func() {
@@ -376,7 +797,7 @@ var _ = Describe("User", func() {
 				recorder := httptest.NewRecorder()
 				user := NewUser(&fakeUserService{
 					user: nil,
-					err:  nil,
+					err:  services.ErrNotFound,
 				})
 
 				handler := http.HandlerFunc(user.Detail)
@@ -400,6 +821,45 @@ var _ = Describe("User", func() {
 			})
 		})
 
+		Context("merged", func() {
+			const target = "0a4f1f8e-51b5-4a8a-9a8e-1f4b0d1c2e3f"
+
+			var recorder *httptest.ResponseRecorder
+
+			BeforeEach(func() {
+				req, err := http.NewRequest(
+					http.MethodGet,
+					fmt.Sprintf("/v1/users/%v", id),
+					nil)
+
+				if err != nil {
+					panic(err)
+				}
+
+				req = mux.SetURLVars(req, map[string]string{
+					"id": id,
+				})
+
+				recorder = httptest.NewRecorder()
+				user := NewUser(&fakeUserService{
+					err:        services.ErrNotFound,
+					mergedInto: target,
+				})
+
+				handler := http.HandlerFunc(user.Detail)
+				handler.ServeHTTP(recorder, req)
+			})
+
+			It("redirects to surviving user", func() {
+				Expect(recorder.Header().Get("Location")).
+					To(Equal(fmt.Sprintf("/v1/users/%v", target)))
+			})
+
+			It("returns http status code MovedPermanently", func() {
+				Expect(recorder.Code).To(Equal(http.StatusMovedPermanently))
+			})
+		})
+
 		Context("error in service call", func() {
 			var statusCode int
 			var res errorResult
@@ -445,11 +905,133 @@ var _ = Describe("User", func() {
 			})
 		})
 	})
+
+	Describe("Merge", func() {
+		const other = "0a4f1f8e-51b5-4a8a-9a8e-1f4b0d1c2e3f"
+
+		var recorder *httptest.ResponseRecorder
+
+		serve := func(srv *fakeUserService, body string) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				fmt.Sprintf("/users/%v/merge", id),
+				strings.NewReader(body))
+
+			if err != nil {
+				panic(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{
+				"id": id,
+			})
+
+			recorder = httptest.NewRecorder()
+			http.HandlerFunc(NewUser(srv).Merge).ServeHTTP(recorder, req)
+		}
+
+		Context("success", func() {
+			var res models.User
+
+			BeforeEach(func() {
+				serve(&fakeUserService{
+					user: &models.User{
+						ID:       id,
+						Email:    email,
+						Consents: make([]models.Consent, 0),
+						Version:  "abc",
+					},
+				}, fmt.Sprintf(`{"user_id":"%v"}`, other))
+
+				if err := json.NewDecoder(recorder.Body).Decode(&res); err != nil {
+					panic(err)
+				}
+			})
+
+			It("returns surviving user", func() {
+				Expect(res.ID).To(Equal(id))
+			})
+
+			It("returns version of consents", func() {
+				Expect(recorder.Header().Get("ETag")).To(Equal(`"abc"`))
+			})
+
+			It("returns http status code OK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("invalid body", func() {
+			BeforeEach(func() {
+				serve(&fakeUserService{}, `{"user_id":"foo"}`)
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				serve(
+					&fakeUserService{err: services.ErrNotFound},
+					fmt.Sprintf(`{"user_id":"%v"}`, other))
+			})
+
+			It("returns http status code NotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("non-existent user to merge", func() {
+			BeforeEach(func() {
+				serve(
+					&fakeUserService{err: services.ErrUserNotFound},
+					fmt.Sprintf(`{"user_id":"%v"}`, other))
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("same user", func() {
+			BeforeEach(func() {
+				serve(
+					&fakeUserService{err: services.ErrSameUser},
+					fmt.Sprintf(`{"user_id":"%v"}`, id))
+			})
+
+			It("returns http status code UnprocessableEntity", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		Context("broken chain", func() {
+			BeforeEach(func() {
+				serve(
+					&fakeUserService{err: fmt.Errorf(
+						"%w: %s: %s",
+						services.ErrChainBroken,
+						other,
+						services.ChainHashMismatch)},
+					fmt.Sprintf(`{"user_id":"%v"}`, other))
+			})
+
+			It("returns http status code Conflict", func() {
+				Expect(recorder.Code).To(Equal(http.StatusConflict))
+				Expect(recorder.Body.String()).To(ContainSubstring("Event chain is broken"))
+			})
+		})
+	})
 })
 
 type fakeUserService struct {
-	user *models.User
-	err  error
+	user       *models.User
+	device     *models.Device
+	created    bool
+	mergedInto string
+	hold       *models.LegalHold
+	err        error
 }
 
 func (srv fakeUserService) Create(
@@ -458,6 +1040,12 @@ func (srv fakeUserService) Create(
 	return srv.user, srv.err
 }
 
+func (srv fakeUserService) Upsert(
+	_ context.Context,
+	_ *models.UserCreateRequest) (*models.User, bool, error) {
+	return srv.user, srv.created, srv.err
+}
+
 func (srv fakeUserService) Delete(_ context.Context, _ string) error {
 	return srv.err
 }
@@ -467,3 +1055,141 @@ func (srv fakeUserService) Detail(
 	_ string) (*models.User, error) {
 	return srv.user, srv.err
 }
+
+func (srv fakeUserService) DetailByIdentifier(
+	_ context.Context,
+	_ models.Identifier) (*models.User, error) {
+	return srv.user, srv.err
+}
+
+func (srv fakeUserService) AttachIdentifier(
+	_ context.Context,
+	_ string,
+	_ models.Identifier) (bool, error) {
+	return srv.created, srv.err
+}
+
+func (srv fakeUserService) DetachIdentifier(
+	_ context.Context,
+	_ string,
+	_ models.Identifier) error {
+	return srv.err
+}
+
+func (srv fakeUserService) DetailByPhone(
+	_ context.Context,
+	_ string) (*models.User, error) {
+	return srv.user, srv.err
+}
+
+func (srv fakeUserService) AttachPhone(
+	_ context.Context,
+	_ string,
+	phone models.Phone) (*models.Phone, bool, error) {
+	if srv.err != nil {
+		return nil, false, srv.err
+	}
+
+	return &phone, srv.created, nil
+}
+
+func (srv fakeUserService) DetachPhone(
+	_ context.Context,
+	_ string,
+	_ string) error {
+	return srv.err
+}
+
+func (srv fakeUserService) Merge(
+	_ context.Context,
+	_ string,
+	_ *models.UserMergeRequest) (*models.User, error) {
+	return srv.user, srv.err
+}
+
+func (srv fakeUserService) MergedInto(
+	_ context.Context,
+	_ string) (string, error) {
+	if srv.mergedInto == "" {
+		return "", services.ErrNotFound
+	}
+
+	return srv.mergedInto, nil
+}
+
+func (srv fakeUserService) Device(
+	_ context.Context,
+	_ string) (*models.Device, error) {
+	return srv.device, srv.err
+}
+
+func (srv fakeUserService) Identify(
+	_ context.Context,
+	_ string,
+	_ *models.DeviceIdentifyRequest) (*models.User, error) {
+	return srv.user, srv.err
+}
+
+func (srv fakeUserService) Export(
+	_ context.Context,
+	_ string) (*models.UserExport, error) {
+	if srv.err != nil {
+		return nil, srv.err
+	}
+
+	generatedAt := time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC)
+
+	return &models.UserExport{
+		GeneratedAt: generatedAt,
+		TenantID:    services.DefaultTenant,
+		User:        *srv.user,
+		LegalHold:   models.LegalHold{UserID: srv.user.ID},
+		Devices:     make([]models.ExportDevice, 0),
+		Events:      make([]models.ExportEvent, 0),
+		Tombstones: []models.ExportTombstone{
+			{
+				EventID:  "5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9",
+				Sequence: 1,
+				Hash:     "hash",
+				PurgedAt: generatedAt,
+				MergedFrom: &models.EventOrigin{
+					UserID:   "9c1d2e3f-4a5b-4c6d-8e7f-0a1b2c3d4e5f",
+					DeviceID: "device-1",
+				},
+			},
+		},
+		Receipts:     make([]models.Receipt, 0),
+		EmailChanges: make([]models.EmailChange, 0),
+		Merges:       make([]models.UserMerge, 0),
+	}, nil
+}
+
+func (srv fakeUserService) LegalHold(
+	_ context.Context,
+	id string) (*models.LegalHold, error) {
+	if srv.err != nil {
+		return nil, srv.err
+	}
+
+	if srv.hold != nil {
+		return srv.hold, nil
+	}
+
+	return &models.LegalHold{UserID: id}, nil
+}
+
+func (srv fakeUserService) PlaceLegalHold(
+	_ context.Context,
+	id string) (*models.LegalHold, error) {
+	if srv.err != nil {
+		return nil, srv.err
+	}
+
+	placedAt := time.Date(2026, time.October, 1, 10, 0, 0, 0, time.UTC)
+
+	return &models.LegalHold{UserID: id, Held: true, PlacedAt: &placedAt}, nil
+}
+
+func (srv fakeUserService) ReleaseLegalHold(_ context.Context, _ string) error {
+	return srv.err
+}
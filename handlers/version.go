@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var (
+	unversionedDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	unversionedSunsetAt     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+type apiVersion struct {
+	name string
+	user func(user *models.User) interface{}
+}
+
+var v1 = apiVersion{
+	name: "v1",
+	user: func(user *models.User) interface{} {
+		return user
+	},
+}
+
+type versionContextKey struct{}
+
+func withVersion(version apiVersion) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), versionContextKey{}, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func versionOf(r *http.Request) apiVersion {
+	if version, ok := r.Context().Value(versionContextKey{}).(apiVersion); ok {
+		return version
+	}
+
+	return v1
+}
+
+func deprecated(successor apiVersion) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(
+				"Deprecation",
+				fmt.Sprintf("@%d", unversionedDeprecatedAt.Unix()))
+			w.Header().Set(
+				"Sunset",
+				unversionedSunsetAt.Format(http.TimeFormat))
+			w.Header().Set(
+				"Link",
+				fmt.Sprintf(
+					`</%s%s>; rel="successor-version"`,
+					successor.name,
+					strings.TrimSuffix(r.URL.Path, "/")))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+
+	"github.com/kazimanzurrashid/consents-api-go/logging"
 )
 
 func writeError(w http.ResponseWriter, statusCode int, errors ...string) {
@@ -10,14 +13,21 @@ func writeError(w http.ResponseWriter, statusCode int, errors ...string) {
 	w.WriteHeader(statusCode)
 
 	res, _ := json.Marshal(struct {
-		Errors []string `json:"errors"`
+		Errors    []string `json:"errors"`
+		RequestID string   `json:"request_id,omitempty"`
 	}{
-		Errors: errors,
+		Errors:    errors,
+		RequestID: w.Header().Get(logging.RequestIDHeader),
 	})
 
 	_, _ = w.Write(res)
 }
 
+func writeServerError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "request failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "Internal server error")
+}
+
 func writeSuccess(w http.ResponseWriter, statusCode int, payload interface{}) {
 	res, err := json.Marshal(payload)
 
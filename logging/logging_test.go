@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logging", func() {
+	Describe("Redact", func() {
+		It("masks email addresses", func() {
+			Expect(Redact("duplicate user@example.com found")).
+				To(Equal("duplicate u***@example.com found"))
+		})
+
+		It("keeps other text intact", func() {
+			Expect(Redact("connection refused")).To(Equal("connection refused"))
+		})
+	})
+
+	Describe("New", func() {
+		var output string
+
+		BeforeEach(func() {
+			var buf bytes.Buffer
+
+			logger := New(&buf, slog.LevelInfo)
+			logger.Error(
+				"create user@example.com failed",
+				"email", "user@example.com",
+				"error", fmt.Errorf("user@example.com exists"))
+
+			output = buf.String()
+		})
+
+		It("writes json", func() {
+			Expect(output).To(HavePrefix("{"))
+		})
+
+		It("does not leak email addresses", func() {
+			Expect(output).NotTo(ContainSubstring("user@example.com"))
+			Expect(output).To(ContainSubstring("u***@example.com"))
+		})
+	})
+})
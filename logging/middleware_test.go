@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		incomingID string
+
+		recorder  *httptest.ResponseRecorder
+		contextID string
+		entry     map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		incomingID = ""
+	})
+
+	JustBeforeEach(func() {
+		var buf bytes.Buffer
+
+		router := mux.NewRouter()
+		router.Use(RouteMiddleware)
+		router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			contextID = RequestID(r.Context())
+			w.WriteHeader(http.StatusNoContent)
+		}).Methods(http.MethodDelete)
+
+		req, err := http.NewRequest(
+			http.MethodDelete,
+			"/users/7b5a3155-7a73-42de-b87e-23f50a10180a",
+			nil)
+
+		if err != nil {
+			panic(err)
+		}
+
+		if incomingID != "" {
+			req.Header.Set(RequestIDHeader, incomingID)
+		}
+
+		recorder = httptest.NewRecorder()
+		Middleware(New(&buf, slog.LevelInfo))(router).ServeHTTP(recorder, req)
+
+		entry = make(map[string]interface{})
+
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			panic(err)
+		}
+	})
+
+	Context("without incoming request id", func() {
+		It("generates and echoes request id", func() {
+			id := recorder.Header().Get(RequestIDHeader)
+
+			Expect(id).NotTo(BeEmpty())
+			Expect(contextID).To(Equal(id))
+			Expect(entry["request_id"]).To(Equal(id))
+		})
+
+		It("logs route, status and user", func() {
+			Expect(entry["route"]).To(Equal("/users/{id}"))
+			Expect(entry["status"]).To(BeNumerically("==", http.StatusNoContent))
+			Expect(entry["user_id"]).To(Equal("7b5a3155-7a73-42de-b87e-23f50a10180a"))
+			Expect(entry).To(HaveKey("latency"))
+		})
+	})
+
+	Context("with incoming request id", func() {
+		BeforeEach(func() {
+			incomingID = "upstream-123"
+		})
+
+		It("honours incoming request id", func() {
+			Expect(recorder.Header().Get(RequestIDHeader)).To(Equal(incomingID))
+			Expect(entry["request_id"]).To(Equal(incomingID))
+		})
+	})
+
+	Context("with invalid incoming request id", func() {
+		BeforeEach(func() {
+			incomingID = "bad id with spaces"
+		})
+
+		It("replaces it", func() {
+			Expect(recorder.Header().Get(RequestIDHeader)).
+				NotTo(Equal(incomingID))
+		})
+	})
+})
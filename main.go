@@ -2,30 +2,39 @@ package main
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
-	gh "github.com/gorilla/handlers"
+	"github.com/XSAM/otelsql"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 
 	"github.com/kazimanzurrashid/consents-api-go/handlers"
+	"github.com/kazimanzurrashid/consents-api-go/logging"
+	"github.com/kazimanzurrashid/consents-api-go/metrics"
+	"github.com/kazimanzurrashid/consents-api-go/models"
 	"github.com/kazimanzurrashid/consents-api-go/services"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
 )
 
 func main() {
+	logLevel := new(slog.LevelVar)
+	logger := logging.New(os.Stdout, logLevel)
+	slog.SetDefault(logger)
+
 	_, currentFile, _, _ := runtime.Caller(0)
 	currentDir := path.Dir(currentFile)
 
@@ -34,12 +43,17 @@ func main() {
 
 		if _, err := os.Stat(envFile); err == nil {
 			if err := godotenv.Load(envFile); err != nil {
-				log.Fatalf("env file load error: %v", err)
+				fatal("env file load error", err)
 				return
 			}
 		}
 	}
 
+	if err := logLevel.UnmarshalText(
+		[]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		logLevel.Set(slog.LevelInfo)
+	}
+
 	pgConnectionString := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		os.Getenv("POSTGRES_HOST"),
@@ -48,10 +62,24 @@ func main() {
 		os.Getenv("POSTGRES_PASSWORD"),
 		os.Getenv("POSTGRES_DB"))
 
-	db, err := sql.Open("postgres", pgConnectionString)
+	shutdownTracing, err := tracing.Setup(context.Background())
+
+	if err != nil {
+		fatal("tracing setup error", err)
+		return
+	}
+
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
+
+	db, err := otelsql.Open(
+		"postgres",
+		pgConnectionString,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
 
 	if err != nil {
-		log.Fatalf("postgres open error: %v", err)
+		fatal("postgres open error", err)
 		return
 	}
 
@@ -61,7 +89,7 @@ func main() {
 
 	if err = db.Ping(); err != nil {
 		closeDB()
-		log.Printf("db ping error: %v", err)
+		slog.Error("db ping error", "error", err)
 		return
 	}
 
@@ -72,56 +100,127 @@ func main() {
 
 	if err != nil {
 		closeDB()
-		log.Printf("schema file read error: %v", err)
+		slog.Error("schema file read error", "error", err)
 		return
 	}
 
 	if _, err := db.Exec(string(schema)); err != nil {
 		closeDB()
-		log.Printf("schema file execute error: %v", err)
+		slog.Error("schema file execute error", "error", err)
+		return
+	}
+
+	if len(os.Args) > 1 {
+		code := runCommand(db, os.Args[1:])
+		closeDB()
+		_ = shutdownTracing(context.Background())
+		os.Exit(code)
+	}
+
+	metrics.RegisterDB(db)
+
+	signer, err := loadReceiptSigner()
+
+	if err != nil {
+		closeDB()
+		fatal("receipt signing key load error", err)
+		return
+	}
+
+	retired, err := loadRetiredReceiptKeys()
+
+	if err != nil {
+		closeDB()
+		fatal("retired receipt key load error", err)
 		return
 	}
 
-	us := services.NewUser(db)
-	es := services.NewEvent(db)
-	uh := handlers.NewUser(us)
-	eh := handlers.NewEvent(es)
+	var issuer *services.ReceiptIssuer
+
+	if signer != nil {
+		issuer = &services.ReceiptIssuer{
+			Signer:       signer,
+			Jurisdiction: os.Getenv("RECEIPT_JURISDICTION"),
+			PolicyURL:    os.Getenv("RECEIPT_POLICY_URL"),
+			Controller: models.ReceiptController{
+				PIIController:    os.Getenv("RECEIPT_CONTROLLER"),
+				Contact:          os.Getenv("RECEIPT_CONTROLLER_CONTACT"),
+				Address:          os.Getenv("RECEIPT_CONTROLLER_ADDRESS"),
+				Email:            os.Getenv("RECEIPT_CONTROLLER_EMAIL"),
+				Phone:            os.Getenv("RECEIPT_CONTROLLER_PHONE"),
+				PIIControllerURL: os.Getenv("RECEIPT_CONTROLLER_URL"),
+			},
+		}
+	} else {
+		slog.Warn("receipt signing key not configured, receipts are not issued")
+	}
+
+	us := services.NewUser(db, issuer)
+	es := services.NewEvent(db, issuer)
+	rs := services.NewReceipt(db, signer, retired...)
+	ts := services.NewTenant(db)
+	hs := services.NewHealth(db)
+	hh := handlers.NewHealth(hs)
 
 	router := mux.NewRouter()
+	router.Use(logging.RouteMiddleware)
+	router.Use(otelmux.Middleware(tracing.ServiceName))
+	router.Use(metrics.RouteMiddleware)
 
-	router.HandleFunc("/users", uh.Create).Methods(http.MethodPost)
-	router.HandleFunc("/users/{id}", uh.Delete).Methods(http.MethodDelete)
-	router.HandleFunc("/users/{id}", uh.Detail).Methods(http.MethodGet)
-	router.HandleFunc("/events", eh.Create).Methods(http.MethodPost)
-	router.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json;charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(struct {
-			Result    string `json:"result"`
-			Timestamp string `json:"timestamp"`
-		}{
-			Result:    "ok",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
-	}).Methods(http.MethodGet)
+	handlers.Register(router, handlers.Handlers{
+		User:       handlers.NewUser(us),
+		Event:      handlers.NewEvent(es),
+		Consent:    handlers.NewConsent(es),
+		Identifier: handlers.NewIdentifier(us),
+		Phone:      handlers.NewPhone(us),
+		Device:     handlers.NewDevice(us),
+		Receipt:    handlers.NewReceipt(rs),
+		Tenant:     handlers.NewTenant(ts, os.Getenv("TENANT_HEADER_ENABLED") == "true"),
+		Health:     hh,
+	})
+
+	handlers.RegisterOperational(router, os.Getenv("OPENAPI_DOCS") == "true")
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%s", os.Getenv("PORT")),
-		Handler:           gh.LoggingHandler(os.Stdout, router),
+		Handler:           logging.Middleware(logger)(metrics.Middleware(router)),
 		ReadHeaderTimeout: time.Second,
 	}
 
 	go func() {
 		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 			closeDB()
-			log.Fatalf("server listen and serve error: %v", err)
+			fatal("server listen and serve error", err)
 		}
 	}()
 
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	if interval, err := time.ParseDuration(
+		os.Getenv("RETENTION_PURGE_INTERVAL")); err == nil && interval > 0 {
+		worker := newPurgeWorker(
+			services.NewRetention(db),
+			interval,
+			purgeOptions())
+
+		hh.AddCheck("retention", worker.check)
+
+		go worker.run(purgeCtx)
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
+	stopPurge()
+	hh.Drain()
+
+	if delay, err := time.ParseDuration(
+		os.Getenv("SHUTDOWN_DRAIN_DELAY")); err == nil {
+		time.Sleep(delay)
+	}
+
 	shutdownCtx, shutdownRelease := context.WithTimeout(
 		context.Background(),
 		10*time.Second)
@@ -130,6 +229,53 @@ func main() {
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		closeDB()
-		log.Printf("server shutdown error: %v", err)
+		slog.Error("server shutdown error", "error", err)
+	}
+}
+
+func loadReceiptSigner() (*services.ReceiptSigner, error) {
+	keyFile := os.Getenv("RECEIPT_SIGNING_KEY_FILE")
+
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	key, err := os.ReadFile(keyFile)
+
+	if err != nil {
+		return nil, err
 	}
+
+	return services.NewReceiptSigner(key)
+}
+
+func loadRetiredReceiptKeys() ([]models.JWK, error) {
+	keys := make([]models.JWK, 0)
+
+	for _, keyFile := range strings.Split(os.Getenv("RECEIPT_RETIRED_KEY_FILES"), ",") {
+		if keyFile = strings.TrimSpace(keyFile); keyFile == "" {
+			continue
+		}
+
+		key, err := os.ReadFile(keyFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		jwk, err := services.NewReceiptVerificationKey(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return keys, nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
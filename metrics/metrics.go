@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "consents"
+
+var (
+	httpRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route, method and status.",
+		},
+		[]string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method"})
+
+	consentChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "consent_changes_total",
+			Help:      "Number of recorded consent changes by consent and direction.",
+		},
+		[]string{"consent", "direction"})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequests, httpDuration, consentChanges)
+}
+
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ConsentChanged(consentID string, enabled bool) {
+	direction := "opt_out"
+
+	if enabled {
+		direction = "opt_in"
+	}
+
+	consentChanges.WithLabelValues(consentID, direction).Inc()
+}
+
+type contextKey struct{}
+
+type routeInfo struct {
+	template string
+}
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &routeInfo{template: "unmatched"}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(
+			recorder,
+			r.WithContext(context.WithValue(r.Context(), contextKey{}, info)))
+
+		httpRequests.
+			WithLabelValues(info.template, r.Method, strconv.Itoa(recorder.status)).
+			Inc()
+		httpDuration.
+			WithLabelValues(info.template, r.Method).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(contextKey{}).(*routeInfo); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					info.template = template
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
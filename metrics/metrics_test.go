@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Metrics", func() {
+	Describe("Middleware", func() {
+		serve := func(path string) {
+			router := mux.NewRouter()
+			router.Use(RouteMiddleware)
+			router.HandleFunc("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}).Methods(http.MethodGet)
+
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+
+			if err != nil {
+				panic(err)
+			}
+
+			Middleware(router).ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		BeforeEach(func() {
+			httpRequests.Reset()
+		})
+
+		It("labels requests by route template", func() {
+			serve("/users/7b5a3155-7a73-42de-b87e-23f50a10180a")
+
+			count := testutil.ToFloat64(
+				httpRequests.WithLabelValues("/users/{id}", http.MethodGet, "404"))
+
+			Expect(count).To(Equal(float64(1)))
+		})
+
+		It("counts requests matching no route", func() {
+			serve("/unknown")
+
+			count := testutil.ToFloat64(
+				httpRequests.WithLabelValues("unmatched", http.MethodGet, "404"))
+
+			Expect(count).To(Equal(float64(1)))
+		})
+	})
+
+	Describe("ConsentChanged", func() {
+		BeforeEach(func() {
+			consentChanges.Reset()
+
+			ConsentChanged("email_notifications", true)
+			ConsentChanged("email_notifications", false)
+			ConsentChanged("email_notifications", false)
+		})
+
+		It("counts opt-ins", func() {
+			count := testutil.ToFloat64(
+				consentChanges.WithLabelValues("email_notifications", "opt_in"))
+
+			Expect(count).To(Equal(float64(1)))
+		})
+
+		It("counts opt-outs", func() {
+			count := testutil.ToFloat64(
+				consentChanges.WithLabelValues("email_notifications", "opt_out"))
+
+			Expect(count).To(Equal(float64(2)))
+		})
+	})
+})
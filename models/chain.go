@@ -0,0 +1,34 @@
+package models
+
+type ChainBreak struct {
+	EventID  string `json:"event_id,omitempty"`
+	Sequence int64  `json:"sequence,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+type ChainVerification struct {
+	UserID    string      `json:"user_id"`
+	Events    int         `json:"events"`
+	Purged    int         `json:"purged"`
+	Unchained int         `json:"unchained"`
+	Valid     bool        `json:"valid"`
+	BrokenAt  *ChainBreak `json:"broken_at,omitempty"`
+}
+
+type ChainReport struct {
+	Users  int                `json:"users"`
+	Events int                `json:"events"`
+	Purged int                `json:"purged"`
+	Valid  bool               `json:"valid"`
+	Broken *ChainVerification `json:"broken,omitempty"`
+}
+
+type TenantChainReport struct {
+	TenantID string `json:"tenant_id"`
+	ChainReport
+}
+
+type ChainAudit struct {
+	Valid   bool                `json:"valid"`
+	Tenants []TenantChainReport `json:"tenants"`
+}
@@ -1,10 +1,21 @@
 package models
 
-import "github.com/go-ozzo/ozzo-validation"
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation"
+)
+
+const MaxOccurredAtSkew = 5 * time.Minute
+
+var consentIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
 
 type Consent struct {
-	ID      string `json:"id"`
-	Enabled bool   `json:"enabled"`
+	ID         string     `json:"id"`
+	Enabled    bool       `json:"enabled"`
+	OccurredAt *time.Time `json:"occurred_at,omitempty"`
 }
 
 func (c Consent) Validate() error {
@@ -13,5 +24,21 @@ func (c Consent) Validate() error {
 		validation.Field(
 			&c.ID,
 			validation.Required,
-			validation.In(ConsentEmail, ConsentSMS)))
+			validation.Length(1, 64),
+			validation.Match(consentIDPattern)),
+		validation.Field(&c.OccurredAt, validation.By(notInFuture)))
+}
+
+func notInFuture(value interface{}) error {
+	occurredAt, ok := value.(*time.Time)
+
+	if !ok || occurredAt == nil {
+		return nil
+	}
+
+	if occurredAt.After(time.Now().Add(MaxOccurredAtSkew)) {
+		return errors.New("must not be in the future")
+	}
+
+	return nil
 }
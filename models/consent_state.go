@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type ConsentState struct {
+	ID          string    `json:"id"`
+	Enabled     bool      `json:"enabled"`
+	PhoneNumber string    `json:"phone_number,omitempty"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -47,5 +49,48 @@ var _ = Describe("Consent", func() {
 				})
 			})
 		})
+
+		Describe("OccurredAt", func() {
+			Context("nil", func() {
+				var err error
+
+				BeforeEach(func() {
+					c := Consent{ID: ConsentEmail}
+					err = c.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("in the past", func() {
+				var err error
+
+				BeforeEach(func() {
+					occurredAt := time.Now().Add(-24 * time.Hour)
+					c := Consent{ID: ConsentEmail, OccurredAt: &occurredAt}
+					err = c.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("too far in the future", func() {
+				var err error
+
+				BeforeEach(func() {
+					occurredAt := time.Now().Add(MaxOccurredAtSkew + time.Minute)
+					c := Consent{ID: ConsentEmail, OccurredAt: &occurredAt}
+					err = c.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+		})
 	})
 })
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation"
+)
+
+type ConsentUpdateRequest struct {
+	Enabled    *bool      `json:"enabled"`
+	OccurredAt *time.Time `json:"occurred_at,omitempty"`
+}
+
+func (cur ConsentUpdateRequest) Validate() error {
+	return validation.ValidateStruct(
+		&cur,
+		validation.Field(&cur.Enabled, validation.NotNil),
+		validation.Field(&cur.OccurredAt, validation.By(notInFuture)))
+}
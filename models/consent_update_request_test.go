@@ -0,0 +1,39 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConsentUpdateRequest", func() {
+	Describe("Validate", func() {
+		Describe("Enabled", func() {
+			Context("nil", func() {
+				var err error
+
+				BeforeEach(func() {
+					cur := new(ConsentUpdateRequest)
+					err = cur.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("false", func() {
+				var err error
+
+				BeforeEach(func() {
+					enabled := false
+					cur := ConsentUpdateRequest{Enabled: &enabled}
+					err = cur.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+	})
+})
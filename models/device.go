@@ -0,0 +1,8 @@
+package models
+
+type Device struct {
+	ID       string    `json:"id"`
+	UserID   string    `json:"user_id,omitempty"`
+	Consents []Consent `json:"consents"`
+	Version  string    `json:"-"`
+}
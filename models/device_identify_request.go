@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+)
+
+type DeviceIdentifyRequest struct {
+	UserID string `json:"user_id"`
+}
+
+func (dir DeviceIdentifyRequest) Validate() error {
+	return validation.ValidateStruct(
+		&dir,
+		validation.Field(&dir.UserID, validation.Required, is.UUID))
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Event struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	ConsentID    string    `json:"consent_id"`
+	Sequence     int64     `json:"sequence"`
+	Enabled      bool      `json:"enabled"`
+	PhoneNumber  string    `json:"phone_number,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	PreviousHash string    `json:"previous_hash,omitempty"`
+	Hash         string    `json:"hash,omitempty"`
+}
+
+type EventCreateResult struct {
+	Events    []Event        `json:"events"`
+	Changed   []string       `json:"changed"`
+	Consents  []Consent      `json:"consents"`
+	States    []ConsentState `json:"-"`
+	ReceiptID string         `json:"receipt_id,omitempty"`
+	Version   string         `json:"-"`
+}
+
+type EventBatchItemResult struct {
+	Events    []Event
+	Changed   []string
+	ReceiptID string
+	Err       error
+}
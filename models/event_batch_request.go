@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "best_effort"
+
+	MaxBatchItems = 1000
+)
+
+type EventBatchRequest struct {
+	Mode  string               `json:"mode"`
+	Items []EventCreateRequest `json:"items"`
+}
+
+func (ebr EventBatchRequest) Validate() error {
+	return validation.ValidateStruct(
+		&ebr,
+		validation.Field(
+			&ebr.Mode,
+			validation.In(BatchAtomic, BatchBestEffort)),
+		validation.Field(
+			&ebr.Items,
+			validation.Required,
+			validation.Length(1, MaxBatchItems),
+			validation.Skip))
+}
+
+func (ebr EventBatchRequest) Atomic() bool {
+	return ebr.Mode != BatchBestEffort
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventBatchRequest", func() {
+	Describe("Validate", func() {
+		Describe("Mode", func() {
+			Context("empty", func() {
+				var err error
+
+				BeforeEach(func() {
+					ebr := EventBatchRequest{
+						Items: []EventCreateRequest{{}},
+					}
+					err = ebr.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("invalid value", func() {
+				var err error
+
+				BeforeEach(func() {
+					ebr := EventBatchRequest{
+						Mode:  "eventually",
+						Items: []EventCreateRequest{{}},
+					}
+					err = ebr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+		})
+
+		Describe("Items", func() {
+			Context("empty", func() {
+				var err error
+
+				BeforeEach(func() {
+					ebr := EventBatchRequest{Mode: BatchAtomic}
+					err = ebr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("too many", func() {
+				var err error
+
+				BeforeEach(func() {
+					ebr := EventBatchRequest{
+						Mode:  BatchBestEffort,
+						Items: make([]EventCreateRequest, MaxBatchItems+1),
+					}
+					err = ebr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("invalid item", func() {
+				var err error
+
+				BeforeEach(func() {
+					ebr := EventBatchRequest{
+						Mode:  BatchBestEffort,
+						Items: []EventCreateRequest{{}},
+					}
+					err = ebr.Validate()
+				})
+
+				It("leaves item validation to the caller", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+	})
+
+	Describe("Atomic", func() {
+		It("defaults to atomic", func() {
+			Expect(EventBatchRequest{}.Atomic()).To(BeTrue())
+		})
+
+		It("is not atomic in best effort mode", func() {
+			Expect(EventBatchRequest{Mode: BatchBestEffort}.Atomic()).To(BeFalse())
+		})
+	})
+})
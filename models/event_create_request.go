@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 )
@@ -18,14 +20,73 @@ func (ecu EventCreateUser) Validate() error {
 			is.UUID))
 }
 
+type EventCreateDevice struct {
+	ID string `json:"id"`
+}
+
+func (ecd EventCreateDevice) Validate() error {
+	return validation.ValidateStruct(
+		&ecd,
+		validation.Field(
+			&ecd.ID,
+			validation.Required,
+			validation.Length(1, 128)))
+}
+
 type EventCreateRequest struct {
-	User     *EventCreateUser `json:"user"`
-	Consents *[]Consent       `json:"consents"`
+	User          *EventCreateUser   `json:"user,omitempty"`
+	Device        *EventCreateDevice `json:"device,omitempty"`
+	Consents      *[]Consent         `json:"consents"`
+	SkipUnchanged bool               `json:"skip_unchanged"`
+	IfMatch       []string           `json:"-"`
 }
 
 func (ecr EventCreateRequest) Validate() error {
+	userRules := []validation.Rule{validation.Required}
+
+	if ecr.Device != nil {
+		userRules = []validation.Rule{validation.By(blankWithDevice)}
+	}
+
 	return validation.ValidateStruct(
 		&ecr,
-		validation.Field(&ecr.User, validation.Required),
-		validation.Field(&ecr.Consents, validation.Required))
+		validation.Field(&ecr.User, userRules...),
+		validation.Field(&ecr.Device),
+		validation.Field(
+			&ecr.Consents,
+			validation.Required,
+			validation.By(uniqueConsents)))
+}
+
+func blankWithDevice(value interface{}) error {
+	if user, ok := value.(*EventCreateUser); ok && user != nil {
+		return errors.New("must be blank when device is given")
+	}
+
+	return nil
+}
+
+func uniqueConsents(value interface{}) error {
+	var consents []Consent
+
+	switch value := value.(type) {
+	case []Consent:
+		consents = value
+	case *[]Consent:
+		if value != nil {
+			consents = *value
+		}
+	}
+
+	seen := make(map[string]bool, len(consents))
+
+	for _, consent := range consents {
+		if seen[consent.ID] {
+			return errors.New("must not contain the same consent more than once")
+		}
+
+		seen[consent.ID] = true
+	}
+
+	return nil
 }
@@ -52,6 +52,63 @@ var _ = Describe("EventCreateUser", func() {
 
 var _ = Describe("EventCreateRequest", func() {
 	Describe("Validate", func() {
+		Describe("Device", func() {
+			Context("instead of user", func() {
+				var err error
+
+				BeforeEach(func() {
+					ecr := EventCreateRequest{
+						Device: &EventCreateDevice{ID: "cookie-4f1c"},
+						Consents: &[]Consent{
+							{ID: ConsentEmail},
+						},
+					}
+					err = ecr.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("together with user", func() {
+				var err error
+
+				BeforeEach(func() {
+					ecr := EventCreateRequest{
+						User:   &EventCreateUser{ID: "7b5a3155-7a73-42de-b87e-23f50a10180a"},
+						Device: &EventCreateDevice{ID: "cookie-4f1c"},
+						Consents: &[]Consent{
+							{ID: ConsentEmail},
+						},
+					}
+					err = ecr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("empty id", func() {
+				var err error
+
+				BeforeEach(func() {
+					ecr := EventCreateRequest{
+						Device: &EventCreateDevice{},
+						Consents: &[]Consent{
+							{ID: ConsentEmail},
+						},
+					}
+					err = ecr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+		})
+
 		Describe("User", func() {
 			Context("nil", func() {
 				var err error
@@ -122,6 +179,25 @@ var _ = Describe("EventCreateRequest", func() {
 					Expect(err).To(BeNil())
 				})
 			})
+
+			Context("duplicate consent", func() {
+				var err error
+
+				BeforeEach(func() {
+					ecr := EventCreateRequest{
+						User: &EventCreateUser{ID: "7b5a3155-7a73-42de-b87e-23f50a10180a"},
+						Consents: &[]Consent{
+							{ID: ConsentEmail, Enabled: true},
+							{ID: ConsentEmail, Enabled: false},
+						},
+					}
+					err = ecr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
 		})
 	})
 })
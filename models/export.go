@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+const (
+	ExportJSON = "json"
+	ExportZip  = "zip"
+)
+
+type UserExport struct {
+	GeneratedAt  time.Time         `json:"generated_at"`
+	TenantID     string            `json:"tenant_id"`
+	User         User              `json:"user"`
+	LegalHold    LegalHold         `json:"legal_hold"`
+	Devices      []ExportDevice    `json:"devices"`
+	Events       []ExportEvent     `json:"events"`
+	Tombstones   []ExportTombstone `json:"tombstones"`
+	Receipts     []Receipt         `json:"receipts"`
+	EmailChanges []EmailChange     `json:"email_changes"`
+	Merges       []UserMerge       `json:"merges"`
+}
+
+type ExportDevice struct {
+	ID       string    `json:"id"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type ExportEvent struct {
+	Event
+	ReceiptID  string       `json:"receipt_id,omitempty"`
+	MergedFrom *EventOrigin `json:"merged_from,omitempty"`
+}
+
+type ExportTombstone struct {
+	EventID      string       `json:"event_id"`
+	Sequence     int64        `json:"sequence"`
+	PreviousHash string       `json:"previous_hash,omitempty"`
+	Hash         string       `json:"hash,omitempty"`
+	PurgedAt     time.Time    `json:"purged_at"`
+	MergedFrom   *EventOrigin `json:"merged_from,omitempty"`
+}
+
+type EventOrigin struct {
+	UserID   string `json:"user_id"`
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+type EmailChange struct {
+	PreviousEmail string     `json:"previous_email,omitempty"`
+	Email         string     `json:"email"`
+	ChangedAt     *time.Time `json:"changed_at,omitempty"`
+}
+
+type UserMerge struct {
+	FromID     string    `json:"from_id"`
+	Email      string    `json:"email,omitempty"`
+	ExternalID string    `json:"external_id,omitempty"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	MergedAt   time.Time `json:"merged_at"`
+}
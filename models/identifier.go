@@ -0,0 +1,28 @@
+package models
+
+import (
+	"regexp"
+
+	"github.com/go-ozzo/ozzo-validation"
+)
+
+var namespacePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+type Identifier struct {
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+}
+
+func (i Identifier) Validate() error {
+	return validation.ValidateStruct(
+		&i,
+		validation.Field(
+			&i.Namespace,
+			validation.Required,
+			validation.Length(1, 32),
+			validation.Match(namespacePattern)),
+		validation.Field(
+			&i.ID,
+			validation.Required,
+			validation.Length(1, 128)))
+}
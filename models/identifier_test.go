@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Identifier", func() {
+	Describe("Validate", func() {
+		Describe("Namespace", func() {
+			Context("empty", func() {
+				var err error
+
+				BeforeEach(func() {
+					i := Identifier{ID: "42"}
+					err = i.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("invalid value", func() {
+				var err error
+
+				BeforeEach(func() {
+					i := Identifier{Namespace: "Shop Floor", ID: "42"}
+					err = i.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("valid value", func() {
+				var err error
+
+				BeforeEach(func() {
+					i := Identifier{Namespace: "mobile_app", ID: "42"}
+					err = i.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+
+		Describe("ID", func() {
+			Context("empty", func() {
+				var err error
+
+				BeforeEach(func() {
+					i := Identifier{Namespace: "crm"}
+					err = i.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("too long", func() {
+				var err error
+
+				BeforeEach(func() {
+					i := Identifier{Namespace: "crm", ID: strings.Repeat("x", 129)}
+					err = i.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"regexp"
+
+	"github.com/go-ozzo/ozzo-validation"
+)
+
+var phoneNumberPattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+type Phone struct {
+	Number  string `json:"number"`
+	Primary bool   `json:"primary"`
+}
+
+func (p Phone) Validate() error {
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(
+			&p.Number,
+			validation.Required,
+			validation.Match(phoneNumberPattern)))
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Phone", func() {
+	Describe("Validate", func() {
+		Describe("Number", func() {
+			Context("empty", func() {
+				var err error
+
+				BeforeEach(func() {
+					p := Phone{}
+					err = p.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("missing country code", func() {
+				var err error
+
+				BeforeEach(func() {
+					p := Phone{Number: "4155550100"}
+					err = p.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("too long", func() {
+				var err error
+
+				BeforeEach(func() {
+					p := Phone{Number: "+1415555010012345"}
+					err = p.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("valid value", func() {
+				var err error
+
+				BeforeEach(func() {
+					p := Phone{Number: "+447911123456"}
+					err = p.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+	})
+})
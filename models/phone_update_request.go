@@ -0,0 +1,9 @@
+package models
+
+type PhoneUpdateRequest struct {
+	Primary bool `json:"primary"`
+}
+
+func (pur PhoneUpdateRequest) Validate() error {
+	return nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+const ConsentReceiptVersion = "KI-CR-v1.1.0"
+
+type ConsentReceipt struct {
+	Version          string              `json:"version"`
+	Jurisdiction     string              `json:"jurisdiction"`
+	ConsentTimestamp int64               `json:"consentTimestamp"`
+	CollectionMethod string              `json:"collectionMethod"`
+	ConsentReceiptID string              `json:"consentReceiptID"`
+	PublicKey        string              `json:"publicKey,omitempty"`
+	Language         string              `json:"language"`
+	PIIPrincipalID   string              `json:"piiPrincipalId"`
+	PIIControllers   []ReceiptController `json:"piiControllers"`
+	PolicyURL        string              `json:"policyUrl"`
+	Services         []ReceiptService    `json:"services"`
+	Sensitive        bool                `json:"sensitive"`
+	SPICat           []string            `json:"spiCat"`
+}
+
+type ReceiptController struct {
+	PIIController    string `json:"piiController"`
+	OnBehalf         bool   `json:"onBehalf"`
+	Contact          string `json:"contact"`
+	Address          string `json:"address"`
+	Email            string `json:"email"`
+	Phone            string `json:"phone"`
+	PIIControllerURL string `json:"piiControllerUrl,omitempty"`
+}
+
+type ReceiptService struct {
+	Service  string           `json:"service"`
+	Purposes []ReceiptPurpose `json:"purposes"`
+}
+
+type ReceiptPurpose struct {
+	Purpose              string   `json:"purpose"`
+	PurposeCategory      []string `json:"purposeCategory"`
+	ConsentType          string   `json:"consentType"`
+	PIICategory          []string `json:"piiCategory"`
+	PrimaryPurpose       bool     `json:"primaryPurpose"`
+	Termination          string   `json:"termination"`
+	ThirdPartyDisclosure bool     `json:"thirdPartyDisclosure"`
+	Enabled              bool     `json:"enabled"`
+	EventID              string   `json:"eventId"`
+}
+
+type Receipt struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id"`
+	KeyID     string         `json:"key_id"`
+	Receipt   ConsentReceipt `json:"receipt"`
+	JWS       string         `json:"jws"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
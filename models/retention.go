@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type LegalHold struct {
+	UserID   string     `json:"user_id"`
+	Held     bool       `json:"held"`
+	PlacedAt *time.Time `json:"placed_at,omitempty"`
+}
+
+type PurgeReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Events  int64         `json:"events"`
+	Tenants []TenantPurge `json:"tenants"`
+}
+
+type TenantPurge struct {
+	TenantID string           `json:"tenant_id"`
+	Events   int64            `json:"events"`
+	Consents map[string]int64 `json:"consents"`
+}
@@ -6,14 +6,23 @@ import (
 )
 
 type UserCreateRequest struct {
-	Email string `json:"email"`
+	Email      string    `json:"email,omitempty"`
+	Phone      string    `json:"phone,omitempty"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Consents   []Consent `json:"consents,omitempty"`
 }
 
 func (ucr UserCreateRequest) Validate() error {
+	emailRules := []validation.Rule{is.Email}
+
+	if ucr.Phone == "" {
+		emailRules = append([]validation.Rule{validation.Required}, emailRules...)
+	}
+
 	return validation.ValidateStruct(
 		&ucr,
-		validation.Field(
-			&ucr.Email,
-			validation.Required,
-			is.Email))
+		validation.Field(&ucr.Email, emailRules...),
+		validation.Field(&ucr.Phone, validation.Match(phoneNumberPattern)),
+		validation.Field(&ucr.ExternalID, validation.Length(1, 128)),
+		validation.Field(&ucr.Consents, validation.By(uniqueConsents)))
 }
@@ -47,5 +47,89 @@ var _ = Describe("UserCreateRequest", func() {
 				})
 			})
 		})
+
+		Describe("Phone", func() {
+			Context("without email", func() {
+				var err error
+
+				BeforeEach(func() {
+					ucr := UserCreateRequest{Phone: "+14155550100"}
+					err = ucr.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("not in E.164 format", func() {
+				var err error
+
+				BeforeEach(func() {
+					ucr := UserCreateRequest{Phone: "(415) 555-0100"}
+					err = ucr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+		})
+
+		Describe("Consents", func() {
+			Context("invalid consent", func() {
+				var err error
+
+				BeforeEach(func() {
+					ucr := UserCreateRequest{
+						Email:    "user@example.com",
+						Consents: []Consent{{ID: "foo bar"}},
+					}
+					err = ucr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("duplicate consent", func() {
+				var err error
+
+				BeforeEach(func() {
+					ucr := UserCreateRequest{
+						Email: "user@example.com",
+						Consents: []Consent{
+							{ID: ConsentSMS, Enabled: true},
+							{ID: ConsentSMS, Enabled: false},
+						},
+					}
+					err = ucr.Validate()
+				})
+
+				It("returns error", func() {
+					Expect(err).NotTo(BeNil())
+				})
+			})
+
+			Context("valid consents", func() {
+				var err error
+
+				BeforeEach(func() {
+					ucr := UserCreateRequest{
+						Email: "user@example.com",
+						Consents: []Consent{
+							{ID: ConsentEmail, Enabled: true},
+							{ID: ConsentSMS, Enabled: false},
+						},
+					}
+					err = ucr.Validate()
+				})
+
+				It("does not return error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
 	})
 })
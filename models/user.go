@@ -1,7 +1,12 @@
 package models
 
 type User struct {
-	ID       string    `json:"id"`
-	Email    string    `json:"email"`
-	Consents []Consent `json:"consents"`
+	ID          string       `json:"id"`
+	Email       string       `json:"email,omitempty"`
+	ExternalID  string       `json:"external_id,omitempty"`
+	Consents    []Consent    `json:"consents"`
+	Phones      []Phone      `json:"phones"`
+	Identifiers []Identifier `json:"identifiers"`
+	ReceiptID   string       `json:"receipt_id,omitempty"`
+	Version     string       `json:"-"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+)
+
+type UserMergeRequest struct {
+	UserID string `json:"user_id"`
+}
+
+func (umr UserMergeRequest) Validate() error {
+	return validation.ValidateStruct(
+		&umr,
+		validation.Field(&umr.UserID, validation.Required, is.UUID))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+func purgeOptions() services.PurgeOptions {
+	batchSize, err := strconv.Atoi(os.Getenv("RETENTION_PURGE_BATCH_SIZE"))
+
+	if err != nil {
+		batchSize = services.DefaultPurgeBatchSize
+	}
+
+	return services.PurgeOptions{
+		BatchSize: batchSize,
+		DryRun:    os.Getenv("RETENTION_PURGE_DRY_RUN") == "true",
+	}
+}
+
+type purgeWorker struct {
+	retention   services.Retention
+	interval    time.Duration
+	staleAfter  time.Duration
+	options     services.PurgeOptions
+	lastSuccess atomic.Int64
+}
+
+func newPurgeWorker(
+	rs services.Retention,
+	interval time.Duration,
+	options services.PurgeOptions) *purgeWorker {
+
+	staleAfter, err := time.ParseDuration(
+		os.Getenv("RETENTION_PURGE_STALE_AFTER"))
+
+	if err != nil || staleAfter <= 0 {
+		staleAfter = 3 * interval
+	}
+
+	w := &purgeWorker{
+		retention:  rs,
+		interval:   interval,
+		staleAfter: staleAfter,
+		options:    options,
+	}
+
+	w.lastSuccess.Store(time.Now().UnixNano())
+
+	return w
+}
+
+func (w *purgeWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := w.retention.Purge(ctx, w.options)
+
+			if res != nil {
+				slog.Info(
+					"retention purge",
+					"dry_run", res.DryRun,
+					"events", res.Events,
+					"tenants", len(res.Tenants))
+			}
+
+			if err == nil {
+				w.lastSuccess.Store(time.Now().UnixNano())
+			} else if !errors.Is(err, context.Canceled) {
+				slog.Error("retention purge error", "error", err)
+			}
+		}
+	}
+}
+
+func (w *purgeWorker) check(_ context.Context) error {
+	since := time.Since(time.Unix(0, w.lastSuccess.Load()))
+
+	if since > w.staleAfter {
+		return fmt.Errorf(
+			"last successful purge was %s ago",
+			since.Round(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/services"
+)
+
+var _ = Describe("purgeWorker", func() {
+	const interval = time.Minute
+
+	var worker *purgeWorker
+
+	BeforeEach(func() {
+		worker = newPurgeWorker(
+			&fakeRetention{},
+			interval,
+			services.PurgeOptions{})
+	})
+
+	It("defaults stale after to three intervals", func() {
+		Expect(worker.staleAfter).To(Equal(3 * interval))
+	})
+
+	Describe("check", func() {
+		Context("never ran", func() {
+			It("does not return any error", func() {
+				Expect(worker.check(context.TODO())).To(Succeed())
+			})
+		})
+
+		Context("ran recently", func() {
+			var cancel context.CancelFunc
+
+			BeforeEach(func() {
+				var ctx context.Context
+
+				worker.interval = time.Millisecond
+				worker.lastSuccess.Store(
+					time.Now().Add(-2 * worker.staleAfter).UnixNano())
+
+				ctx, cancel = context.WithCancel(context.Background())
+
+				go worker.run(ctx)
+
+				Eventually(func() error {
+					return worker.check(context.TODO())
+				}).Should(Succeed())
+			})
+
+			AfterEach(func() {
+				cancel()
+			})
+
+			It("does not return any error", func() {
+				Expect(worker.check(context.TODO())).To(Succeed())
+			})
+		})
+
+		Context("last success older than stale after", func() {
+			BeforeEach(func() {
+				worker.lastSuccess.Store(
+					time.Now().Add(-worker.staleAfter - time.Minute).UnixNano())
+			})
+
+			It("returns stale error", func() {
+				Expect(worker.check(context.TODO())).
+					To(MatchError(ContainSubstring("last successful purge was")))
+			})
+		})
+	})
+})
+
+type fakeRetention struct{}
+
+func (srv *fakeRetention) Purge(
+	_ context.Context,
+	options services.PurgeOptions) (*models.PurgeReport, error) {
+	return &models.PurgeReport{DryRun: options.DryRun}, nil
+}
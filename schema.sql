@@ -21,3 +21,647 @@ create table if not exists events
     constraint "uq_userId_consentId_createdAt"
         unique (user_id, consent_id, created_at)
 );
+
+create table if not exists schema_migrations
+(
+    version    integer                  not null
+        constraint pk_schema_migrations
+            primary key,
+    applied_at timestamp with time zone not null default now()
+);
+
+insert into schema_migrations (version)
+values (1)
+on conflict do nothing;
+
+alter table events
+    add column if not exists occurred_at timestamp with time zone;
+
+update events
+set occurred_at = created_at
+where occurred_at is null;
+
+alter table events
+    alter column occurred_at set not null;
+
+insert into schema_migrations (version)
+values (2)
+on conflict do nothing;
+
+alter table events
+    add column if not exists sequence bigint;
+
+alter table users
+    add column if not exists event_sequence bigint not null default 0;
+
+do
+$$
+    begin
+        if not exists(select 1 from schema_migrations where version = 3) then
+            update events e
+            set sequence = s.sequence
+            from (select id,
+                         row_number() over (
+                             partition by user_id
+                             order by occurred_at, created_at, id) as sequence
+                  from events) s
+            where e.id = s.id;
+
+            update users u
+            set event_sequence = (select coalesce(max(sequence), 0)
+                                  from events
+                                  where user_id = u.id);
+
+            alter table events
+                alter column sequence set not null;
+
+            alter table events
+                drop constraint if exists "uq_userId_consentId_createdAt";
+
+            alter table events
+                add constraint "uq_userId_sequence"
+                    unique (user_id, sequence);
+
+            insert into schema_migrations (version)
+            values (3);
+        end if;
+    end
+$$;
+
+alter table users
+    add column if not exists external_id varchar(128);
+
+do
+$$
+    begin
+        if not exists(select 1 from schema_migrations where version = 4) then
+            alter table users
+                add constraint uq_external_id
+                    unique (external_id);
+
+            insert into schema_migrations (version)
+            values (4);
+        end if;
+    end
+$$;
+
+create table if not exists user_identifiers
+(
+    namespace   varchar(32)              not null,
+    external_id varchar(128)             not null,
+    user_id     char(36)                 not null
+        constraint user_identifiers_users
+            references users
+            on delete cascade,
+    created_at  timestamp with time zone not null default now(),
+    constraint pk_user_identifiers
+        primary key (namespace, external_id)
+);
+
+create index if not exists ix_user_identifiers_user_id
+    on user_identifiers (user_id);
+
+insert into schema_migrations (version)
+values (5)
+on conflict do nothing;
+
+alter table users
+    alter column email drop not null;
+
+create table if not exists user_phones
+(
+    number     varchar(16)              not null
+        constraint pk_user_phones
+            primary key,
+    user_id    char(36)                 not null
+        constraint user_phones_users
+            references users
+            on delete cascade,
+    is_primary boolean                  not null default false,
+    created_at timestamp with time zone not null default now()
+);
+
+create index if not exists ix_user_phones_user_id
+    on user_phones (user_id);
+
+create unique index if not exists uq_user_phones_primary
+    on user_phones (user_id)
+    where is_primary;
+
+alter table events
+    add column if not exists phone_number varchar(16);
+
+insert into schema_migrations (version)
+values (6)
+on conflict do nothing;
+
+create table if not exists user_merges
+(
+    from_id     char(36)                 not null
+        constraint pk_user_merges
+            primary key,
+    to_id       char(36)                 not null
+        constraint user_merges_users
+            references users
+            on delete cascade,
+    email       varchar(128),
+    external_id varchar(128),
+    merged_at   timestamp with time zone not null default now()
+);
+
+create index if not exists ix_user_merges_to_id
+    on user_merges (to_id);
+
+insert into schema_migrations (version)
+values (7)
+on conflict do nothing;
+
+alter table users
+    add column if not exists anonymous boolean not null default false;
+
+create table if not exists devices
+(
+    id         varchar(128)             not null
+        constraint pk_devices
+            primary key,
+    user_id    char(36)                 not null
+        constraint devices_users
+            references users
+            on delete cascade,
+    created_at timestamp with time zone not null default now()
+);
+
+create index if not exists ix_devices_user_id
+    on devices (user_id);
+
+insert into schema_migrations (version)
+values (8)
+on conflict do nothing;
+
+create table if not exists tenants
+(
+    id         varchar(64)              not null
+        constraint pk_tenants
+            primary key,
+    created_at timestamp with time zone not null default now()
+);
+
+insert into tenants (id)
+values ('default')
+on conflict do nothing;
+
+create table if not exists tenant_consents
+(
+    tenant_id  varchar(64)              not null
+        constraint tenant_consents_tenants
+            references tenants
+            on delete cascade,
+    consent_id varchar(64)              not null,
+    created_at timestamp with time zone not null default now(),
+    constraint pk_tenant_consents
+        primary key (tenant_id, consent_id)
+);
+
+create table if not exists api_keys
+(
+    hash       char(64)                 not null
+        constraint pk_api_keys
+            primary key,
+    tenant_id  varchar(64)              not null
+        constraint api_keys_tenants
+            references tenants
+            on delete cascade,
+    created_at timestamp with time zone not null default now(),
+    revoked_at timestamp with time zone
+);
+
+create index if not exists ix_api_keys_tenant_id
+    on api_keys (tenant_id);
+
+do
+$$
+    begin
+        if not exists(select 1 from pg_roles where rolname = 'consents_tenant') then
+            create role consents_tenant nologin;
+        end if;
+
+        if not pg_has_role(current_user, 'consents_tenant', 'member') then
+            execute format('grant consents_tenant to %I', current_user);
+        end if;
+    end
+$$;
+
+do
+$$
+    declare
+        name text;
+    begin
+        if not exists(select 1 from schema_migrations where version = 9) then
+            insert into tenant_consents (tenant_id, consent_id)
+            values ('default', 'email_notifications'),
+                   ('default', 'sms_notifications')
+            on conflict do nothing;
+
+            alter table users
+                add column tenant_id varchar(64) not null default 'default'
+                    constraint users_tenants
+                        references tenants;
+
+            alter table users
+                drop constraint uq_email;
+
+            alter table users
+                add constraint uq_email
+                    unique (tenant_id, email);
+
+            alter table users
+                drop constraint uq_external_id;
+
+            alter table users
+                add constraint uq_external_id
+                    unique (tenant_id, external_id);
+
+            alter table users
+                add constraint uq_users_id_tenant_id
+                    unique (id, tenant_id);
+
+            foreach name in array array ['events', 'user_identifiers', 'user_phones', 'user_merges', 'devices']
+                loop
+                    execute format(
+                            'alter table %I add column tenant_id varchar(64) not null default %L',
+                            name,
+                            'default');
+                end loop;
+
+            alter table events
+                drop constraint events_users;
+
+            alter table events
+                add constraint events_users
+                    foreign key (user_id, tenant_id)
+                        references users (id, tenant_id);
+
+            alter table events
+                add constraint events_tenant_consents
+                    foreign key (tenant_id, consent_id)
+                        references tenant_consents (tenant_id, consent_id);
+
+            alter table user_identifiers
+                drop constraint user_identifiers_users;
+
+            alter table user_identifiers
+                add constraint user_identifiers_users
+                    foreign key (user_id, tenant_id)
+                        references users (id, tenant_id)
+                        on delete cascade;
+
+            alter table user_identifiers
+                drop constraint pk_user_identifiers;
+
+            alter table user_identifiers
+                add constraint pk_user_identifiers
+                    primary key (tenant_id, namespace, external_id);
+
+            alter table user_phones
+                drop constraint user_phones_users;
+
+            alter table user_phones
+                add constraint user_phones_users
+                    foreign key (user_id, tenant_id)
+                        references users (id, tenant_id)
+                        on delete cascade;
+
+            alter table user_phones
+                drop constraint pk_user_phones;
+
+            alter table user_phones
+                add constraint pk_user_phones
+                    primary key (tenant_id, number);
+
+            alter table user_merges
+                drop constraint user_merges_users;
+
+            alter table user_merges
+                add constraint user_merges_users
+                    foreign key (to_id, tenant_id)
+                        references users (id, tenant_id)
+                        on delete cascade;
+
+            alter table devices
+                drop constraint devices_users;
+
+            alter table devices
+                add constraint devices_users
+                    foreign key (user_id, tenant_id)
+                        references users (id, tenant_id)
+                        on delete cascade;
+
+            alter table devices
+                drop constraint pk_devices;
+
+            alter table devices
+                add constraint pk_devices
+                    primary key (tenant_id, id);
+
+            foreach name in array array ['users', 'events', 'user_identifiers', 'user_phones', 'user_merges', 'devices']
+                loop
+                    execute format(
+                            'alter table %I alter column tenant_id set default current_setting(%L)',
+                            name,
+                            'app.tenant_id');
+                    execute format('alter table %I enable row level security', name);
+                    execute format('alter table %I force row level security', name);
+                    execute format(
+                            'create policy tenant_isolation on %I using (tenant_id = current_setting(%L, true)) with check (tenant_id = current_setting(%L, true))',
+                            name,
+                            'app.tenant_id',
+                            'app.tenant_id');
+                    execute format(
+                            'grant select, insert, update, delete on %I to consents_tenant',
+                            name);
+                end loop;
+
+            insert into schema_migrations (version)
+            values (9);
+        end if;
+    end
+$$;
+
+alter default privileges
+    grant select, insert, update, delete on tables to consents_tenant;
+
+alter table events
+    add column if not exists previous_hash char(64);
+
+alter table events
+    add column if not exists hash char(64);
+
+alter table users
+    add column if not exists event_hash char(64);
+
+alter table user_merges
+    add column if not exists event_hash char(64);
+
+insert into schema_migrations (version)
+values (10)
+on conflict do nothing;
+
+create table if not exists receipts
+(
+    id         char(36)                 not null
+        constraint pk_receipts
+            primary key,
+    tenant_id  varchar(64)              not null default current_setting('app.tenant_id'),
+    user_id    char(36)                 not null,
+    key_id     varchar(64)              not null,
+    receipt    jsonb                    not null,
+    jws        text                     not null,
+    created_at timestamp with time zone not null,
+    constraint receipts_users
+        foreign key (user_id, tenant_id)
+            references users (id, tenant_id)
+            on delete cascade
+);
+
+create index if not exists ix_receipts_user_id
+    on receipts (user_id);
+
+do
+$$
+    begin
+        if not exists(select 1 from schema_migrations where version = 11) then
+            alter table receipts
+                enable row level security;
+
+            alter table receipts
+                force row level security;
+
+            create policy tenant_isolation on receipts
+                using (tenant_id = current_setting('app.tenant_id', true))
+                with check (tenant_id = current_setting('app.tenant_id', true));
+
+            grant select, insert, update, delete on receipts to consents_tenant;
+
+            insert into schema_migrations (version)
+            values (11);
+        end if;
+    end
+$$;
+
+create table if not exists email_changes
+(
+    tenant_id      varchar(64)  not null default current_setting('app.tenant_id'),
+    user_id        char(36)     not null,
+    previous_email varchar(128),
+    email          varchar(128) not null,
+    changed_at     timestamp with time zone default now(),
+    constraint email_changes_users
+        foreign key (user_id, tenant_id)
+            references users (id, tenant_id)
+            on delete cascade
+);
+
+create index if not exists ix_email_changes_user_id
+    on email_changes (user_id);
+
+create or replace function record_email_change() returns trigger
+    language plpgsql
+as
+$$
+begin
+    if new.email is not null and (tg_op = 'INSERT' or new.email is distinct from old.email) then
+        insert into email_changes (tenant_id, user_id, previous_email, email)
+        values (new.tenant_id,
+                new.id,
+                case when tg_op = 'UPDATE' then old.email end,
+                new.email);
+    end if;
+
+    return new;
+end
+$$;
+
+do
+$$
+    declare
+        tenant record;
+    begin
+        if not exists(select 1 from schema_migrations where version = 12) then
+            for tenant in select id from tenants
+                loop
+                    perform set_config('app.tenant_id', tenant.id, true);
+
+                    insert into email_changes (tenant_id, user_id, email, changed_at)
+                    select tenant_id, id, email, null
+                    from users
+                    where email is not null;
+                end loop;
+
+            perform set_config('app.tenant_id', '', true);
+
+            create trigger users_email_changes
+                after insert or update of email
+                on users
+                for each row
+            execute function record_email_change();
+
+            alter table email_changes
+                enable row level security;
+
+            alter table email_changes
+                force row level security;
+
+            create policy tenant_isolation on email_changes
+                using (tenant_id = current_setting('app.tenant_id', true))
+                with check (tenant_id = current_setting('app.tenant_id', true));
+
+            grant select, insert, update, delete on email_changes to consents_tenant;
+
+            insert into schema_migrations (version)
+            values (12);
+        end if;
+    end
+$$;
+
+alter table tenant_consents
+    add column if not exists retention interval;
+
+alter table users
+    add column if not exists legal_hold_at timestamp with time zone;
+
+create table if not exists event_tombstones
+(
+    tenant_id     varchar(64)              not null default current_setting('app.tenant_id'),
+    event_id      char(36)                 not null,
+    user_id       char(36)                 not null,
+    sequence      bigint                   not null,
+    previous_hash char(64),
+    hash          char(64),
+    purged_at     timestamp with time zone not null default now(),
+    constraint pk_event_tombstones
+        primary key (tenant_id, event_id),
+    constraint event_tombstones_users
+        foreign key (user_id, tenant_id)
+            references users (id, tenant_id)
+            on delete cascade
+);
+
+create index if not exists ix_event_tombstones_user_id
+    on event_tombstones (user_id);
+
+create index if not exists ix_events_tenant_id_consent_id_occurred_at
+    on events (tenant_id, consent_id, occurred_at);
+
+do
+$$
+    begin
+        if not exists(select 1 from schema_migrations where version = 13) then
+            alter table event_tombstones
+                enable row level security;
+
+            alter table event_tombstones
+                force row level security;
+
+            create policy tenant_isolation on event_tombstones
+                using (tenant_id = current_setting('app.tenant_id', true))
+                with check (tenant_id = current_setting('app.tenant_id', true));
+
+            grant select, insert, update, delete on event_tombstones to consents_tenant;
+            grant select on tenant_consents to consents_tenant;
+
+            insert into schema_migrations (version)
+            values (13);
+        end if;
+    end
+$$;
+
+do
+$$
+    declare
+        tenant record;
+    begin
+        if not exists(select 1 from schema_migrations where version = 14) then
+            alter table users
+                add column chain_start bigint;
+
+            for tenant in select id from tenants
+                loop
+                    perform set_config('app.tenant_id', tenant.id, true);
+
+                    update users u
+                    set chain_start = coalesce(
+                            (select min(c.sequence)
+                             from (select sequence
+                                   from events
+                                   where user_id = u.id
+                                     and hash is not null
+                                   union all
+                                   select sequence
+                                   from event_tombstones
+                                   where user_id = u.id
+                                     and hash is not null) c),
+                            u.event_sequence + 1);
+                end loop;
+
+            perform set_config('app.tenant_id', '', true);
+
+            alter table users
+                alter column chain_start set default 1;
+
+            alter table users
+                alter column chain_start set not null;
+
+            insert into schema_migrations (version)
+            values (14);
+        end if;
+    end
+$$;
+
+alter table events
+    add column if not exists merged_from char(36);
+
+alter table event_tombstones
+    add column if not exists merged_from char(36);
+
+alter table user_merges
+    add column if not exists device_id varchar(128);
+
+insert into schema_migrations (version)
+values (15)
+on conflict do nothing;
+
+do
+$$
+    declare
+        tenant record;
+    begin
+        if not exists(select 1 from schema_migrations where version = 16) then
+            for tenant in select id from tenants
+                loop
+                    perform set_config('app.tenant_id', tenant.id, true);
+
+                    insert into email_changes (tenant_id, user_id, email, changed_at)
+                    select u.tenant_id,
+                           u.id,
+                           case when f.user_id is null then u.email else f.previous_email end,
+                           null
+                    from users u
+                             left join lateral (select user_id, previous_email
+                                                from email_changes
+                                                where user_id = u.id
+                                                order by changed_at
+                                                limit 1) f on true
+                    where not exists(select 1
+                                     from email_changes c
+                                     where c.user_id = u.id
+                                       and c.changed_at is null)
+                      and case when f.user_id is null then u.email else f.previous_email end is not null;
+                end loop;
+
+            perform set_config('app.tenant_id', '', true);
+
+            insert into schema_migrations (version)
+            values (16);
+        end if;
+    end
+$$;
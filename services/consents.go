@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+type queryer interface {
+	QueryContext(
+		ctx context.Context,
+		query string,
+		args ...interface{}) (*sql.Rows, error)
+
+	QueryRowContext(
+		ctx context.Context,
+		query string,
+		args ...interface{}) *sql.Row
+}
+
+const (
+	primaryPhoneQuery = `(SELECT p.number FROM "user_phones" p WHERE p.user_id = u.id AND p.is_primary)`
+	primaryPhoneScope = `
+AND (consent_id <> '` + models.ConsentSMS + `' OR phone_number IS NOT DISTINCT FROM (
+	SELECT p.number
+	FROM "user_phones" p
+	WHERE p.user_id = "events".user_id
+	AND p.is_primary))`
+)
+
+func offeredConsents(ctx context.Context, q queryer) ([]string, error) {
+	const query = `SELECT consent_id FROM "tenant_consents" WHERE tenant_id = current_setting('app.tenant_id') ORDER BY consent_id`
+
+	rows, err := q.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	consentIDs := make([]string, 0)
+
+	for rows.Next() {
+		var consentID string
+
+		if err := rows.Scan(&consentID); err != nil {
+			return nil, err
+		}
+
+		consentIDs = append(consentIDs, consentID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return consentIDs, nil
+}
+
+func offersConsents(offered []string, consents []models.Consent) error {
+	for _, consent := range consents {
+		found := false
+
+		for _, consentID := range offered {
+			found = found || consentID == consent.ID
+		}
+
+		if !found {
+			return fmt.Errorf("%w: %s", ErrConsentNotOffered, consent.ID)
+		}
+	}
+
+	return nil
+}
+
+func latestConsents(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.Consent, string, error) {
+	states, version, err := latestConsentStates(ctx, q, userID)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return consentsOf(states), version, nil
+}
+
+func consentsOf(states []models.ConsentState) []models.Consent {
+	consents := make([]models.Consent, 0, len(states))
+
+	for _, state := range states {
+		consents = append(consents, models.Consent{
+			ID:      state.ID,
+			Enabled: state.Enabled,
+		})
+	}
+
+	return consents
+}
+
+func latestConsentStates(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.ConsentState, string, error) {
+	const query = `
+SELECT e.id, e.consent_id, e.enabled, e.phone_number, e.occurred_at
+FROM "tenant_consents" c
+JOIN LATERAL (
+	SELECT id, consent_id, enabled, phone_number, occurred_at
+	FROM "events"
+	WHERE user_id = $1
+	AND consent_id = c.consent_id` + primaryPhoneScope + `
+	ORDER BY occurred_at DESC, sequence DESC
+	LIMIT 1) e ON TRUE
+WHERE c.tenant_id = current_setting('app.tenant_id')
+ORDER BY c.consent_id`
+
+	profile, err := userProfile(ctx, q, userID)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	eventRows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() {
+		_ = eventRows.Close()
+	}()
+
+	states := make([]models.ConsentState, 0)
+	eventIDs := make([]string, 0)
+
+	for eventRows.Next() {
+		var (
+			eventID     string
+			state       models.ConsentState
+			phoneNumber sql.NullString
+		)
+
+		if err := eventRows.Scan(
+			&eventID,
+			&state.ID,
+			&state.Enabled,
+			&phoneNumber,
+			&state.ChangedAt); err != nil {
+			return nil, "", err
+		}
+
+		state.PhoneNumber = phoneNumber.String
+		states = append(states, state)
+		eventIDs = append(eventIDs, eventID)
+	}
+
+	if err := eventRows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return states, userVersion(eventIDs, profile), nil
+}
+
+func userProfile(
+	ctx context.Context,
+	q queryer,
+	userID string) (string, error) {
+	const query = `
+SELECT concat(
+	(SELECT coalesce(email, '') || E'\n' || coalesce(external_id, '') FROM "users" WHERE id = $1),
+	E'\n',
+	(SELECT string_agg(namespace || ':' || external_id, ',' ORDER BY namespace, external_id) FROM "user_identifiers" WHERE user_id = $1),
+	E'\n',
+	(SELECT string_agg(number || CASE WHEN is_primary THEN '*' ELSE '' END, ',' ORDER BY number) FROM "user_phones" WHERE user_id = $1))`
+
+	var profile string
+
+	if err := q.QueryRowContext(ctx, query, userID).Scan(&profile); err != nil {
+		return "", err
+	}
+
+	return profile, nil
+}
+
+func latestConsentsOf(
+	ctx context.Context,
+	q queryer,
+	userIDs []string) (map[string][]models.Consent, error) {
+	const query = `
+SELECT DISTINCT ON (user_id, consent_id) user_id, consent_id, enabled
+FROM "events"
+WHERE user_id = ANY($1)` + primaryPhoneScope + `
+ORDER BY user_id, consent_id, occurred_at DESC, sequence DESC`
+
+	rows, err := q.QueryContext(ctx, query, pq.Array(userIDs))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	consents := make(map[string][]models.Consent, len(userIDs))
+
+	for rows.Next() {
+		var (
+			userID  string
+			consent models.Consent
+		)
+
+		if err := rows.Scan(&userID, &consent.ID, &consent.Enabled); err != nil {
+			return nil, err
+		}
+
+		consents[userID] = append(consents[userID], consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return consents, nil
+}
+
+func mergeConsents(
+	current []models.Consent,
+	changes []models.Consent) []models.Consent {
+	merged := append(make([]models.Consent, 0, len(current)), current...)
+
+	for _, change := range changes {
+		found := false
+
+		for index := range merged {
+			if merged[index].ID == change.ID {
+				merged[index].Enabled = change.Enabled
+				found = true
+			}
+		}
+
+		if !found {
+			merged = append(merged, models.Consent{
+				ID:      change.ID,
+				Enabled: change.Enabled,
+			})
+		}
+	}
+
+	return merged
+}
+
+func userVersion(eventIDs []string, profile string) string {
+	hash := sha256.Sum256([]byte(strings.Join(eventIDs, ",") + "\n" + profile))
+
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrBatchRejected      = errors.New("batch rejected")
+	ErrPhoneNumberTaken   = fmt.Errorf("phone number %w", ErrConflict)
+	ErrEmailTaken         = fmt.Errorf("email %w", ErrConflict)
+	ErrExternalIDTaken    = fmt.Errorf("external id %w", ErrConflict)
+	ErrSameUser           = errors.New("same user")
+	ErrConsentNotOffered  = errors.New("consent not offered")
+	ErrPhoneRequired      = errors.New("phone required")
+	ErrChainBroken        = errors.New("chain broken")
+	ErrLegalHold          = errors.New("legal hold")
+
+	ErrUnsupportedSigningKey = errors.New("unsupported signing key")
+)
+
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqMaxParameters       = 65535
+)
+
+var foreignKeyErrors = map[string]error{
+	"events_users":           ErrUserNotFound,
+	"user_identifiers_users": ErrUserNotFound,
+	"user_phones_users":      ErrUserNotFound,
+	"devices_users":          ErrUserNotFound,
+	"events_tenant_consents": ErrConsentNotOffered,
+}
+
+var uniqueErrors = map[string]error{
+	"pk_user_phones": ErrPhoneNumberTaken,
+	"uq_email":       ErrEmailTaken,
+	"uq_external_id": ErrExternalIDTaken,
+}
+
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		return uniqueViolation(pqErr.Constraint)
+	case pqForeignKeyViolation:
+		if sentinel, ok := foreignKeyErrors[pqErr.Constraint]; ok {
+			return fmt.Errorf("%w: %s", sentinel, pqErr.Constraint)
+		}
+
+		return fmt.Errorf("%w: %s", ErrConflict, pqErr.Constraint)
+	}
+
+	return err
+}
+
+func uniqueViolation(constraint string) error {
+	if sentinel, ok := uniqueErrors[constraint]; ok {
+		return fmt.Errorf("%w: %s", sentinel, constraint)
+	}
+
+	return fmt.Errorf("%w: %s", ErrConflict, constraint)
+}
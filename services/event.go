@@ -3,49 +3,459 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/kazimanzurrashid/consents-api-go/metrics"
 	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
 )
 
 type Event interface {
 	Create(
 		ctx context.Context,
-		request *models.EventCreateRequest) error
+		request *models.EventCreateRequest) (*models.EventCreateResult, error)
+
+	CreateBatch(
+		ctx context.Context,
+		request *models.EventBatchRequest) ([]models.EventBatchItemResult, error)
+
+	Detail(ctx context.Context, id string) (*models.Event, error)
+
+	Consent(
+		ctx context.Context,
+		userID string,
+		consentID string) (*models.ConsentState, error)
+
+	VerifyChain(
+		ctx context.Context,
+		userID string) (*models.ChainVerification, error)
+
+	VerifyChains(ctx context.Context) (*models.ChainReport, error)
 }
 
 type PostgresEvent struct {
-	db *sql.DB
+	db       *sql.DB
+	receipts *ReceiptIssuer
 }
 
-func NewEvent(db *sql.DB) Event {
-	return &PostgresEvent{db}
+func NewEvent(db *sql.DB, receipts *ReceiptIssuer) Event {
+	return &PostgresEvent{db, receipts}
 }
 
 func (e *PostgresEvent) Create(
 	ctx context.Context,
-	request *models.EventCreateRequest) error {
-	const query = `INSERT INTO "events"(id, user_id, consent_id, created_at, enabled) VALUES($1, $2, $3, $4, $5)`
+	request *models.EventCreateRequest) (*models.EventCreateResult, error) {
+	ctx, span := tracing.Start(ctx, "PostgresEvent.Create")
+	defer span.End()
 
-	tx, err := e.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelDefault})
+	tx, err := beginTx(ctx, e.db)
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	userID, err := subjectOf(ctx, tx, request)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	consents := *request.Consents
+	precondition := len(request.IfMatch) > 0 &&
+		!matchesETag(request.IfMatch, "*")
+
+	if request.SkipUnchanged || precondition {
+		if err := lockUser(ctx, tx, userID); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+
+		current, version, err := latestConsents(ctx, tx, userID)
+
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+
+		if precondition && !matchesETag(request.IfMatch, version) {
+			_ = tx.Rollback()
+			return nil, ErrPreconditionFailed
+		}
+
+		if request.SkipUnchanged {
+			consents = changedConsents(consents, current)
+		}
+	}
+
+	sequence, phoneNumber, head, err := allocateSequence(
+		ctx,
+		tx,
+		userID,
+		len(consents))
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err := requirePhone(consents, phoneNumber); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	events := newEvents(
+		userID,
+		consents,
+		sequence,
+		phoneNumber,
+		time.Now().UTC().Truncate(time.Microsecond))
+	heads := map[string]string{userID: head}
+	chainEvents(events, heads)
+
+	if err := appendEvents(ctx, tx, events, heads); err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
 
-	for _, consent := range *request.Consents {
-		if _, err := tx.ExecContext(
-			ctx,
-			query,
-			generateID(),
-			request.User.ID,
-			consent.ID,
-			time.Now().Format(time.RFC3339),
-			consent.Enabled); err != nil {
+	var receiptID string
+
+	if e.receipts != nil && len(events) > 0 {
+		if receiptID, err = e.receipts.issue(ctx, tx, events); err != nil {
 			_ = tx.Rollback()
-			return err
+			return nil, err
+		}
+	}
+
+	states, version, err := latestConsentStates(ctx, tx, userID)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		metrics.ConsentChanged(event.ConsentID, event.Enabled)
+	}
+
+	return &models.EventCreateResult{
+		Events:    events,
+		Changed:   consentIDs(events),
+		Consents:  consentsOf(states),
+		States:    states,
+		Version:   version,
+		ReceiptID: receiptID,
+	}, nil
+}
+
+func (e *PostgresEvent) Detail(
+	ctx context.Context,
+	id string) (*models.Event, error) {
+	const query = `SELECT id, user_id, consent_id, sequence, enabled, phone_number, occurred_at, created_at, previous_hash, hash FROM "events" WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresEvent.Detail")
+	defer span.End()
+
+	var (
+		event        models.Event
+		phoneNumber  sql.NullString
+		previousHash sql.NullString
+		hash         sql.NullString
+	)
+
+	if err := inTenant(ctx, e.db, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, id).Scan(
+			&event.ID,
+			&event.UserID,
+			&event.ConsentID,
+			&event.Sequence,
+			&event.Enabled,
+			&phoneNumber,
+			&event.OccurredAt,
+			&event.CreatedAt,
+			&previousHash,
+			&hash)
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	event.PhoneNumber = phoneNumber.String
+	event.PreviousHash = previousHash.String
+	event.Hash = hash.String
+
+	return &event, nil
+}
+
+func lockUser(ctx context.Context, tx *sql.Tx, userID string) error {
+	const query = `SELECT id FROM "users" WHERE id = $1 FOR UPDATE`
+
+	var id string
+
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func allocateSequence(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID string,
+	count int) (int64, string, string, error) {
+	const query = `UPDATE "users" u SET event_sequence = u.event_sequence + $2 WHERE u.id = $1 RETURNING u.event_sequence, ` + primaryPhoneQuery + `, u.event_hash`
+
+	var (
+		last        int64
+		phoneNumber sql.NullString
+		head        sql.NullString
+	)
+
+	if err := tx.QueryRowContext(ctx, query, userID, count).
+		Scan(&last, &phoneNumber, &head); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", "", ErrUserNotFound
+		}
+
+		return 0, "", "", err
+	}
+
+	return last - int64(count) + 1, phoneNumber.String, head.String, nil
+}
+
+func requirePhone(consents []models.Consent, phoneNumber string) error {
+	if phoneNumber != "" {
+		return nil
+	}
+
+	for _, consent := range consents {
+		if consent.ID == models.ConsentSMS && consent.Enabled {
+			return ErrPhoneRequired
+		}
+	}
+
+	return nil
+}
+
+func newEvents(
+	userID string,
+	consents []models.Consent,
+	sequence int64,
+	phoneNumber string,
+	createdAt time.Time) []models.Event {
+	events := make([]models.Event, 0, len(consents))
+
+	for index, consent := range consents {
+		event := models.Event{
+			ID:         generateID(),
+			UserID:     userID,
+			ConsentID:  consent.ID,
+			Enabled:    consent.Enabled,
+			Sequence:   sequence + int64(index),
+			OccurredAt: createdAt,
+			CreatedAt:  createdAt,
+		}
+
+		if consent.ID == models.ConsentSMS {
+			event.PhoneNumber = phoneNumber
+		}
+
+		if consent.OccurredAt != nil {
+			event.OccurredAt = consent.OccurredAt.UTC().Truncate(time.Microsecond)
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func appendEvents(
+	ctx context.Context,
+	tx *sql.Tx,
+	events []models.Event,
+	heads map[string]string) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	return updateHeads(ctx, tx, heads)
+}
+
+func insertEvents(
+	ctx context.Context,
+	tx *sql.Tx,
+	events []models.Event) error {
+	const (
+		query   = `INSERT INTO "events"(id, user_id, consent_id, sequence, created_at, occurred_at, enabled, phone_number, previous_hash, hash) VALUES`
+		columns = 10
+		maxRows = pqMaxParameters / columns
+	)
+
+	for start := 0; start < len(events); start += maxRows {
+		rows := events[start:min(start+maxRows, len(events))]
+
+		var statement strings.Builder
+		values := make([]interface{}, 0, len(rows)*columns)
+
+		statement.WriteString(query)
+
+		for index, event := range rows {
+			if index > 0 {
+				statement.WriteString(",")
+			}
+
+			offset := index * columns
+			statement.WriteString(fmt.Sprintf(
+				" ($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				offset+1,
+				offset+2,
+				offset+3,
+				offset+4,
+				offset+5,
+				offset+6,
+				offset+7,
+				offset+8,
+				offset+9,
+				offset+10))
+
+			values = append(
+				values,
+				event.ID,
+				event.UserID,
+				event.ConsentID,
+				event.Sequence,
+				event.CreatedAt.Format(time.RFC3339Nano),
+				event.OccurredAt.Format(time.RFC3339Nano),
+				event.Enabled,
+				sql.NullString{
+					String: event.PhoneNumber,
+					Valid:  event.PhoneNumber != "",
+				},
+				sql.NullString{
+					String: event.PreviousHash,
+					Valid:  event.PreviousHash != "",
+				},
+				event.Hash)
+		}
+
+		insertCtx, insertSpan := tracing.Start(ctx, "insert events")
+		insertSpan.SetAttributes(attribute.Int("events.count", len(rows)))
+
+		_, err := tx.ExecContext(insertCtx, statement.String(), values...)
+		tracing.End(insertSpan, err)
+
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	return nil
+}
+
+func consentIDs(events []models.Event) []string {
+	ids := make([]string, 0, len(events))
+
+	for _, event := range events {
+		ids = append(ids, event.ConsentID)
+	}
+
+	return ids
+}
+
+func changedConsents(
+	requested []models.Consent,
+	current []models.Consent) []models.Consent {
+	enabled := make(map[string]bool, len(current))
+
+	for _, consent := range current {
+		enabled[consent.ID] = consent.Enabled
+	}
+
+	changed := make([]models.Consent, 0, len(requested))
+
+	for _, consent := range requested {
+		if value, ok := enabled[consent.ID]; ok && value == consent.Enabled {
+			continue
+		}
+
+		changed = append(changed, consent)
+	}
+
+	return changed
+}
+
+func (e *PostgresEvent) Consent(
+	ctx context.Context,
+	userID string,
+	consentID string) (*models.ConsentState, error) {
+	const query = `
+SELECT e.enabled, e.phone_number, e.occurred_at
+FROM "users" u
+LEFT JOIN LATERAL (
+	SELECT enabled, phone_number, occurred_at
+	FROM "events"
+	WHERE user_id = u.id
+	AND consent_id = $2` + primaryPhoneScope + `
+	ORDER BY occurred_at DESC, sequence DESC
+	LIMIT 1) e ON TRUE
+WHERE u.id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresEvent.Consent")
+	defer span.End()
+
+	var (
+		enabled     sql.NullBool
+		phoneNumber sql.NullString
+		changedAt   sql.NullTime
+	)
+
+	if err := inTenant(ctx, e.db, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, userID, consentID).
+			Scan(&enabled, &phoneNumber, &changedAt)
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	if !enabled.Valid {
+		return nil, ErrNotFound
+	}
+
+	return &models.ConsentState{
+		ID:          consentID,
+		Enabled:     enabled.Bool,
+		PhoneNumber: phoneNumber.String,
+		ChangedAt:   changedAt.Time,
+	}, nil
+}
+
+func matchesETag(tags []string, version string) bool {
+	for _, tag := range tags {
+		if tag == "*" || tag == version {
+			return true
 		}
 	}
 
-	return tx.Commit()
+	return false
 }
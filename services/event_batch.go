@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/kazimanzurrashid/consents-api-go/metrics"
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (e *PostgresEvent) CreateBatch(
+	ctx context.Context,
+	request *models.EventBatchRequest) ([]models.EventBatchItemResult, error) {
+	ctx, span := tracing.Start(ctx, "PostgresEvent.CreateBatch")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("batch.items", len(request.Items)),
+		attribute.Bool("batch.atomic", request.Atomic()))
+
+	tx, err := beginTx(ctx, e.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs, err := subjectsOf(ctx, tx, request.Items)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	sequences, phoneNumbers, heads, err := lockSequences(ctx, tx, userIDs)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	offered, err := offeredConsents(ctx, tx)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	skipUnchanged := false
+
+	for _, item := range request.Items {
+		skipUnchanged = skipUnchanged || item.SkipUnchanged
+	}
+
+	current := make(map[string][]models.Consent)
+
+	if skipUnchanged {
+		if current, err = latestConsentsOf(
+			ctx,
+			tx,
+			sortedKeys(sequences)); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	results := make([]models.EventBatchItemResult, len(request.Items))
+	events := make([]models.Event, 0)
+	rejected := false
+
+	for index, item := range request.Items {
+		userID := userIDs[index]
+		sequence, ok := sequences[userID]
+
+		if !ok {
+			results[index].Err = ErrUserNotFound
+			rejected = true
+			continue
+		}
+
+		consents := *item.Consents
+
+		if err := offersConsents(offered, consents); err != nil {
+			results[index].Err = err
+			rejected = true
+			continue
+		}
+
+		if item.SkipUnchanged {
+			consents = changedConsents(consents, current[userID])
+		}
+
+		if err := requirePhone(consents, phoneNumbers[userID]); err != nil {
+			results[index].Err = err
+			rejected = true
+			continue
+		}
+
+		itemEvents := newEvents(
+			userID,
+			consents,
+			sequence,
+			phoneNumbers[userID],
+			createdAt)
+		chainEvents(itemEvents, heads)
+		sequences[userID] = sequence + int64(len(itemEvents))
+		current[userID] = mergeConsents(current[userID], consents)
+
+		results[index] = models.EventBatchItemResult{
+			Events:  itemEvents,
+			Changed: consentIDs(itemEvents),
+		}
+		events = append(events, itemEvents...)
+	}
+
+	if rejected && request.Atomic() {
+		_ = tx.Rollback()
+
+		for index := range results {
+			results[index].Events = nil
+			results[index].Changed = nil
+		}
+
+		return results, ErrBatchRejected
+	}
+
+	if len(events) > 0 {
+		if err := reserveSequences(ctx, tx, sequences); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := appendEvents(ctx, tx, events, heads); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if e.receipts != nil && len(events) > 0 {
+		receiptIDs, err := e.receipts.issueEach(ctx, tx, events)
+
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+
+		for index := range results {
+			if len(results[index].Events) > 0 {
+				results[index].ReceiptID = receiptIDs[userIDs[index]]
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		metrics.ConsentChanged(event.ConsentID, event.Enabled)
+	}
+
+	return results, nil
+}
+
+func subjectsOf(
+	ctx context.Context,
+	tx *sql.Tx,
+	items []models.EventCreateRequest) ([]string, error) {
+	devices := make(map[string]string)
+	userIDs := make([]string, len(items))
+
+	for index := range items {
+		item := &items[index]
+
+		if item.Device == nil {
+			userIDs[index] = item.User.ID
+			continue
+		}
+
+		userID, ok := devices[item.Device.ID]
+
+		if !ok {
+			subject, err := deviceSubject(ctx, tx, item.Device.ID)
+
+			if err != nil {
+				return nil, err
+			}
+
+			userID = subject
+			devices[item.Device.ID] = subject
+		}
+
+		userIDs[index] = userID
+	}
+
+	return userIDs, nil
+}
+
+func lockSequences(
+	ctx context.Context,
+	tx *sql.Tx,
+	userIDs []string) (
+	map[string]int64,
+	map[string]string,
+	map[string]string,
+	error) {
+	const query = `
+SELECT u.id, u.event_sequence, ` + primaryPhoneQuery + `, u.event_hash
+FROM "users" u
+WHERE u.id = ANY($1)
+ORDER BY u.id
+FOR UPDATE OF u`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(userIDs))
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	sequences := make(map[string]int64, len(userIDs))
+	phoneNumbers := make(map[string]string, len(userIDs))
+	heads := make(map[string]string, len(userIDs))
+
+	for rows.Next() {
+		var (
+			userID      string
+			last        int64
+			phoneNumber sql.NullString
+			head        sql.NullString
+		)
+
+		if err := rows.Scan(
+			&userID,
+			&last,
+			&phoneNumber,
+			&head); err != nil {
+			return nil, nil, nil, err
+		}
+
+		sequences[userID] = last + 1
+		phoneNumbers[userID] = phoneNumber.String
+		heads[userID] = head.String
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return sequences, phoneNumbers, heads, nil
+}
+
+func reserveSequences(
+	ctx context.Context,
+	tx *sql.Tx,
+	sequences map[string]int64) error {
+	const query = `
+UPDATE "users" u
+SET event_sequence = c.next - 1
+FROM unnest($1::text[], $2::bigint[]) AS c(id, next)
+WHERE u.id = c.id`
+
+	userIDs := sortedKeys(sequences)
+	values := make([]int64, 0, len(userIDs))
+
+	for _, userID := range userIDs {
+		values = append(values, sequences[userID])
+	}
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(userIDs), pq.Array(values))
+
+	return err
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
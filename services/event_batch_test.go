@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("Event", func() {
+	Describe("CreateBatch", func() {
+		var (
+			knownUserID   string
+			unknownUserID string
+			req           *models.EventBatchRequest
+
+			db    *sql.DB
+			mock  sqlmock.Sqlmock
+			event Event
+		)
+
+		BeforeEach(func() {
+			knownUserID = "00000000-0000-4000-8000-000000000001"
+			unknownUserID = "00000000-0000-4000-8000-000000000002"
+			req = &models.EventBatchRequest{
+				Items: []models.EventCreateRequest{
+					{
+						User: &models.EventCreateUser{ID: knownUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+					},
+					{
+						User: &models.EventCreateUser{ID: unknownUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+					},
+					{
+						User: &models.EventCreateUser{ID: knownUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentSMS, Enabled: false},
+						},
+					},
+				},
+			}
+
+			db, mock = NewSQLMock()
+			event = NewEvent(db, nil)
+		})
+
+		Context("best effort", func() {
+			var (
+				res []models.EventBatchItemResult
+				e   error
+			)
+
+			BeforeEach(func() {
+				req.Mode = models.BatchBestEffort
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WithArgs(pq.Array([]string{knownUserID, unknownUserID, knownUserID})).
+					WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+						AddRow(knownUserID, 10, nil, nil))
+				expectOfferedConsents(mock, models.ConsentEmail, models.ConsentSMS)
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_sequence").
+					WithArgs(pq.Array([]string{knownUserID}), pq.Array([]int64{13})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"events\"(.+) VALUES \\(.+\\), \\(.+\\)$").
+					WithArgs(
+						sqlmock.AnyArg(),
+						knownUserID,
+						models.ConsentEmail,
+						int64(11),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						false,
+						nil,
+						nil,
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						knownUserID,
+						models.ConsentSMS,
+						int64(12),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						false,
+						nil,
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				res, e = event.CreateBatch(context.TODO(), req)
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+
+			It("returns result for every item", func() {
+				Expect(res).To(HaveLen(3))
+				Expect(res[0].Err).To(BeNil())
+				Expect(res[0].Changed).To(Equal([]string{models.ConsentEmail}))
+				Expect(res[1].Err).To(MatchError(ErrUserNotFound))
+				Expect(res[2].Events[0].Sequence).To(Equal(int64(12)))
+			})
+		})
+
+		Context("atomic", func() {
+			var (
+				res []models.EventBatchItemResult
+				e   error
+			)
+
+			BeforeEach(func() {
+				req.Mode = models.BatchAtomic
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+						AddRow(knownUserID, 0, nil, nil))
+				expectOfferedConsents(mock, models.ConsentEmail, models.ConsentSMS)
+				mock.ExpectRollback()
+
+				res, e = event.CreateBatch(context.TODO(), req)
+			})
+
+			It("returns batch rejected error", func() {
+				Expect(e).To(MatchError(ErrBatchRejected))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+
+			It("returns failing item without recording others", func() {
+				Expect(res[1].Err).To(MatchError(ErrUserNotFound))
+				Expect(res[0].Err).To(BeNil())
+				Expect(res[0].Events).To(BeEmpty())
+			})
+		})
+
+		Context("skip unchanged", func() {
+			var res []models.EventBatchItemResult
+
+			BeforeEach(func() {
+				req.Items = []models.EventCreateRequest{
+					{
+						User: &models.EventCreateUser{ID: knownUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+						SkipUnchanged: true,
+					},
+					{
+						User: &models.EventCreateUser{ID: knownUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+						SkipUnchanged: true,
+					},
+				}
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+						AddRow(knownUserID, 0, nil, nil))
+				expectOfferedConsents(mock, models.ConsentEmail, models.ConsentSMS)
+				mock.ExpectQuery("DISTINCT ON").
+					WillReturnRows(mock.NewRows([]string{"user_id", "consent_id", "enabled"}).
+						AddRow(knownUserID, models.ConsentEmail, true))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_sequence").
+					WithArgs(pq.Array([]string{knownUserID}), pq.Array([]int64{2})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"events\"(.+) VALUES \\(.+\\)$").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				res, _ = event.CreateBatch(context.TODO(), req)
+			})
+
+			It("compares against changes earlier in the batch", func() {
+				Expect(res[0].Changed).To(Equal([]string{models.ConsentEmail}))
+				Expect(res[1].Changed).To(BeEmpty())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("best effort with consent not offered", func() {
+			var (
+				res []models.EventBatchItemResult
+				e   error
+			)
+
+			BeforeEach(func() {
+				req.Mode = models.BatchBestEffort
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+						AddRow(knownUserID, 0, nil, nil).
+						AddRow(unknownUserID, 0, nil, nil))
+				expectOfferedConsents(mock, models.ConsentEmail)
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_sequence").
+					WithArgs(pq.Array([]string{knownUserID, unknownUserID}), pq.Array([]int64{2, 2})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO \"events\"(.+) VALUES \\(.+\\), \\(.+\\)$").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+
+				res, e = event.CreateBatch(context.TODO(), req)
+			})
+
+			It("records offered items", func() {
+				Expect(e).To(BeNil())
+				Expect(res[0].Changed).To(Equal([]string{models.ConsentEmail}))
+				Expect(res[1].Changed).To(Equal([]string{models.ConsentEmail}))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+
+			It("reports item with consent not offered", func() {
+				Expect(res[2].Err).To(MatchError(ErrConsentNotOffered))
+				Expect(res[2].Events).To(BeEmpty())
+			})
+		})
+
+		Context("sms enabled without primary phone", func() {
+			var (
+				res []models.EventBatchItemResult
+				e   error
+			)
+
+			BeforeEach(func() {
+				req.Mode = models.BatchAtomic
+				req.Items = req.Items[2:]
+				(*req.Items[0].Consents)[0].Enabled = true
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+						AddRow(knownUserID, 0, nil, nil))
+				expectOfferedConsents(mock, models.ConsentEmail, models.ConsentSMS)
+				mock.ExpectRollback()
+
+				res, e = event.CreateBatch(context.TODO(), req)
+			})
+
+			It("rejects item with phone required error", func() {
+				Expect(e).To(MatchError(ErrBatchRejected))
+				Expect(res[0].Err).To(MatchError(ErrPhoneRequired))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("error in record insert", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+						AddRow(knownUserID, 0, nil, nil).
+						AddRow(unknownUserID, 0, nil, nil))
+				expectOfferedConsents(mock, models.ConsentEmail, models.ConsentSMS)
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_sequence").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO \"events\"").
+					WillReturnError(fmt.Errorf("insert error"))
+				mock.ExpectRollback()
+
+				_, e = event.CreateBatch(context.TODO(), req)
+			})
+
+			It("returns error", func() {
+				Expect(e).NotTo(BeNil())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+	})
+})
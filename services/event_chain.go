@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+const (
+	ChainMissingHash          = "missing hash"
+	ChainHashMismatch         = "hash mismatch"
+	ChainPreviousHashMismatch = "previous hash mismatch"
+	ChainHeadMismatch         = "head mismatch"
+)
+
+const chainQuery = `
+SELECT u.id, u.event_hash, u.chain_start, e.id, e.consent_id, e.sequence, e.enabled, e.phone_number, e.occurred_at, e.created_at, e.previous_hash, e.hash, e.purged
+FROM "users" u
+LEFT JOIN (
+	SELECT id, user_id, consent_id, sequence, enabled, phone_number, occurred_at, created_at, previous_hash, hash, FALSE AS purged
+	FROM "events"
+	UNION ALL
+	SELECT event_id, user_id, NULL, sequence, NULL, NULL, NULL, NULL, previous_hash, hash, TRUE
+	FROM "event_tombstones"
+) e ON e.user_id = u.id`
+
+func (e *PostgresEvent) VerifyChain(
+	ctx context.Context,
+	userID string) (*models.ChainVerification, error) {
+	const query = chainQuery + `
+WHERE u.id = $1
+ORDER BY e.sequence`
+
+	ctx, span := tracing.Start(ctx, "PostgresEvent.VerifyChain")
+	defer span.End()
+
+	var res *models.ChainVerification
+
+	if err := inTenant(ctx, e.db, func(tx *sql.Tx) error {
+		return walkChains(
+			ctx,
+			tx,
+			query,
+			[]interface{}{userID},
+			func(verification models.ChainVerification) bool {
+				res = &verification
+				return false
+			})
+	}); err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		return nil, ErrNotFound
+	}
+
+	return res, nil
+}
+
+func (e *PostgresEvent) VerifyChains(
+	ctx context.Context) (*models.ChainReport, error) {
+	const query = chainQuery + `
+ORDER BY u.id, e.sequence`
+
+	ctx, span := tracing.Start(ctx, "PostgresEvent.VerifyChains")
+	defer span.End()
+
+	res := &models.ChainReport{Valid: true}
+
+	if err := inTenant(ctx, e.db, func(tx *sql.Tx) error {
+		return walkChains(
+			ctx,
+			tx,
+			query,
+			nil,
+			func(verification models.ChainVerification) bool {
+				res.Users++
+				res.Events += verification.Events
+				res.Purged += verification.Purged
+
+				if !verification.Valid {
+					res.Valid = false
+					res.Broken = &verification
+				}
+
+				return verification.Valid
+			})
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func verifyChainsOf(
+	ctx context.Context,
+	q queryer,
+	userIDs ...string) error {
+	const query = chainQuery + `
+WHERE u.id = ANY($1)
+ORDER BY u.id, e.sequence`
+
+	var broken *models.ChainVerification
+
+	if err := walkChains(
+		ctx,
+		q,
+		query,
+		[]interface{}{pq.Array(userIDs)},
+		func(verification models.ChainVerification) bool {
+			if !verification.Valid {
+				broken = &verification
+			}
+
+			return verification.Valid
+		}); err != nil {
+		return err
+	}
+
+	if broken != nil {
+		return fmt.Errorf(
+			"%w: %s: %s",
+			ErrChainBroken,
+			broken.UserID,
+			broken.BrokenAt.Reason)
+	}
+
+	return nil
+}
+
+func walkChains(
+	ctx context.Context,
+	q queryer,
+	query string,
+	values []interface{},
+	visit func(models.ChainVerification) bool) error {
+	rows, err := q.QueryContext(ctx, query, values...)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		current    *models.ChainVerification
+		head       string
+		last       string
+		chainStart int64
+		started    bool
+	)
+
+	finish := func() bool {
+		if current == nil {
+			return true
+		}
+
+		if current.Valid && head != last {
+			current.Valid = false
+			current.BrokenAt = &models.ChainBreak{Reason: ChainHeadMismatch}
+		}
+
+		return visit(*current)
+	}
+
+	for rows.Next() {
+		var (
+			userID       string
+			userHead     sql.NullString
+			userStart    int64
+			eventID      sql.NullString
+			sequence     sql.NullInt64
+			consentID    sql.NullString
+			enabled      sql.NullBool
+			phoneNumber  sql.NullString
+			occurredAt   sql.NullTime
+			createdAt    sql.NullTime
+			previousHash sql.NullString
+			hash         sql.NullString
+			purged       sql.NullBool
+		)
+
+		if err := rows.Scan(
+			&userID,
+			&userHead,
+			&userStart,
+			&eventID,
+			&consentID,
+			&sequence,
+			&enabled,
+			&phoneNumber,
+			&occurredAt,
+			&createdAt,
+			&previousHash,
+			&hash,
+			&purged); err != nil {
+			return err
+		}
+
+		if current == nil || current.UserID != userID {
+			if !finish() {
+				return nil
+			}
+
+			current = &models.ChainVerification{UserID: userID, Valid: true}
+			head = userHead.String
+			last = ""
+			chainStart = userStart
+			started = false
+		}
+
+		if !eventID.Valid || !current.Valid {
+			continue
+		}
+
+		if purged.Bool {
+			current.Purged++
+		} else {
+			current.Events++
+		}
+
+		event := models.Event{
+			ID:           eventID.String,
+			UserID:       userID,
+			ConsentID:    consentID.String,
+			Sequence:     sequence.Int64,
+			Enabled:      enabled.Bool,
+			PhoneNumber:  phoneNumber.String,
+			OccurredAt:   occurredAt.Time,
+			CreatedAt:    createdAt.Time,
+			PreviousHash: previousHash.String,
+			Hash:         hash.String,
+		}
+
+		reason := ""
+
+		switch {
+		case event.Hash == "" && !started && event.Sequence < chainStart:
+			current.Unchained++
+			continue
+		case event.Hash == "":
+			reason = ChainMissingHash
+		case event.PreviousHash != last:
+			reason = ChainPreviousHashMismatch
+		case !purged.Bool && eventHash(event) != event.Hash:
+			reason = ChainHashMismatch
+		}
+
+		if reason != "" {
+			current.Valid = false
+			current.BrokenAt = &models.ChainBreak{
+				EventID:  event.ID,
+				Sequence: event.Sequence,
+				Reason:   reason,
+			}
+			continue
+		}
+
+		started = true
+		last = event.Hash
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	finish()
+
+	return nil
+}
+
+func eventHash(event models.Event) string {
+	content := strings.Join([]string{
+		event.ID,
+		event.UserID,
+		event.ConsentID,
+		strconv.FormatInt(event.Sequence, 10),
+		strconv.FormatBool(event.Enabled),
+		event.PhoneNumber,
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		event.PreviousHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func chainEvents(events []models.Event, heads map[string]string) {
+	for index := range events {
+		event := &events[index]
+		event.PreviousHash = heads[event.UserID]
+		event.Hash = eventHash(*event)
+		heads[event.UserID] = event.Hash
+	}
+}
+
+func updateHeads(
+	ctx context.Context,
+	tx *sql.Tx,
+	heads map[string]string) error {
+	const query = `
+UPDATE "users" u
+SET event_hash = h.hash
+FROM unnest($1::text[], $2::text[]) AS h(id, hash)
+WHERE u.id = h.id`
+
+	if len(heads) == 0 {
+		return nil
+	}
+
+	userIDs := sortedKeys(heads)
+	hashes := make([]string, 0, len(userIDs))
+
+	for _, userID := range userIDs {
+		hashes = append(hashes, heads[userID])
+	}
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(userIDs), pq.Array(hashes))
+
+	return err
+}
+
+func rechainEvents(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID string,
+	sequence int64,
+	head string) error {
+	const (
+		selectQuery = `
+SELECT id, consent_id, sequence, enabled, phone_number, occurred_at, created_at, hash, purged
+FROM (
+	SELECT id, user_id, consent_id, sequence, enabled, phone_number, occurred_at, created_at, NULL AS hash, FALSE AS purged
+	FROM "events"
+	UNION ALL
+	SELECT event_id, user_id, NULL, sequence, NULL, NULL, NULL, NULL, hash, TRUE
+	FROM "event_tombstones"
+) e
+WHERE user_id = $1 AND sequence >= $2
+ORDER BY sequence`
+		updateQuery = `
+UPDATE "events" e
+SET previous_hash = NULLIF(h.previous_hash, ''), hash = h.hash
+FROM unnest($1::text[], $2::text[], $3::text[]) AS h(id, previous_hash, hash)
+WHERE e.id = h.id`
+		tombstonesQuery = `
+UPDATE "event_tombstones" t
+SET previous_hash = NULLIF(h.previous_hash, ''), hash = h.hash
+FROM unnest($1::text[], $2::text[], $3::text[]) AS h(id, previous_hash, hash)
+WHERE t.event_id = h.id`
+	)
+
+	rows, err := tx.QueryContext(ctx, selectQuery, userID, sequence)
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		events    = make([]models.Event, 0)
+		purged    = make([]bool, 0)
+		scanError error
+	)
+
+	for rows.Next() {
+		var (
+			event       = models.Event{UserID: userID}
+			consentID   sql.NullString
+			enabled     sql.NullBool
+			phoneNumber sql.NullString
+			occurredAt  sql.NullTime
+			createdAt   sql.NullTime
+			hash        sql.NullString
+			tombstone   bool
+		)
+
+		if scanError = rows.Scan(
+			&event.ID,
+			&consentID,
+			&event.Sequence,
+			&enabled,
+			&phoneNumber,
+			&occurredAt,
+			&createdAt,
+			&hash,
+			&tombstone); scanError != nil {
+			break
+		}
+
+		event.ConsentID = consentID.String
+		event.Enabled = enabled.Bool
+		event.PhoneNumber = phoneNumber.String
+		event.OccurredAt = occurredAt.Time
+		event.CreatedAt = createdAt.Time
+		event.Hash = hash.String
+		events = append(events, event)
+		purged = append(purged, tombstone)
+	}
+
+	if scanError == nil {
+		scanError = rows.Err()
+	}
+
+	_ = rows.Close()
+
+	if scanError != nil {
+		return scanError
+	}
+
+	var eventLinks, tombstoneLinks chainLinks
+
+	for index := range events {
+		event := &events[index]
+		event.PreviousHash = head
+
+		if purged[index] {
+			if event.Hash == "" {
+				event.Hash = eventHash(*event)
+			}
+
+			tombstoneLinks.add(*event)
+		} else {
+			event.Hash = eventHash(*event)
+			eventLinks.add(*event)
+		}
+
+		head = event.Hash
+	}
+
+	if err := eventLinks.update(ctx, tx, updateQuery); err != nil {
+		return err
+	}
+
+	if len(tombstoneLinks.ids) > 0 {
+		if err := tombstoneLinks.update(ctx, tx, tombstonesQuery); err != nil {
+			return err
+		}
+	}
+
+	return updateHeads(ctx, tx, map[string]string{userID: head})
+}
+
+type chainLinks struct {
+	ids            []string
+	previousHashes []string
+	hashes         []string
+}
+
+func (l *chainLinks) add(event models.Event) {
+	l.ids = append(l.ids, event.ID)
+	l.previousHashes = append(l.previousHashes, event.PreviousHash)
+	l.hashes = append(l.hashes, event.Hash)
+}
+
+func (l *chainLinks) update(
+	ctx context.Context,
+	tx *sql.Tx,
+	query string) error {
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		pq.Array(l.ids),
+		pq.Array(l.previousHashes),
+		pq.Array(l.hashes))
+
+	return err
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("Event", func() {
+	var (
+		userID     string
+		events     []models.Event
+		head       string
+		chainStart int64
+
+		db    *sql.DB
+		mock  sqlmock.Sqlmock
+		event Event
+	)
+
+	chainColumns := []string{
+		"id",
+		"event_hash",
+		"chain_start",
+		"id",
+		"consent_id",
+		"sequence",
+		"enabled",
+		"phone_number",
+		"occurred_at",
+		"created_at",
+		"previous_hash",
+		"hash",
+		"purged",
+	}
+
+	addRows := func(
+		rows *sqlmock.Rows,
+		userHead string,
+		chained []models.Event) *sqlmock.Rows {
+		for _, e := range chained {
+			var previousHash interface{}
+
+			if e.PreviousHash != "" {
+				previousHash = e.PreviousHash
+			}
+
+			rows.AddRow(
+				e.UserID,
+				userHead,
+				chainStart,
+				e.ID,
+				e.ConsentID,
+				e.Sequence,
+				e.Enabled,
+				nil,
+				e.OccurredAt,
+				e.CreatedAt,
+				previousHash,
+				e.Hash,
+				false)
+		}
+
+		return rows
+	}
+
+	BeforeEach(func() {
+		userID = generateID()
+		chainStart = 1
+		events = newEvents(
+			userID,
+			[]models.Consent{
+				{ID: models.ConsentEmail, Enabled: true},
+				{ID: models.ConsentSMS, Enabled: false},
+			},
+			1,
+			"",
+			time.Now().UTC().Truncate(time.Microsecond))
+		heads := map[string]string{userID: ""}
+		chainEvents(events, heads)
+		head = heads[userID]
+
+		db, mock = NewSQLMock()
+		event = NewEvent(db, nil)
+	})
+
+	Describe("chainEvents", func() {
+		It("links every event to previous one", func() {
+			Expect(events[0].PreviousHash).To(BeEmpty())
+			Expect(events[1].PreviousHash).To(Equal(events[0].Hash))
+			Expect(head).To(Equal(events[1].Hash))
+		})
+
+		It("hashes event content", func() {
+			tampered := events[0]
+			tampered.Enabled = false
+
+			Expect(eventHash(events[0])).To(Equal(events[0].Hash))
+			Expect(eventHash(tampered)).NotTo(Equal(events[0].Hash))
+		})
+	})
+
+	Describe("VerifyChain", func() {
+		var (
+			res *models.ChainVerification
+			e   error
+		)
+
+		verify := func(rows *sqlmock.Rows) {
+			expectTenantTx(mock)
+			mock.ExpectQuery("WHERE u.id = \\$1").
+				WithArgs(userID).
+				WillReturnRows(rows)
+			mock.ExpectCommit()
+
+			res, e = event.VerifyChain(context.TODO(), userID)
+		}
+
+		Context("intact", func() {
+			BeforeEach(func() {
+				verify(addRows(mock.NewRows(chainColumns), head, events))
+			})
+
+			It("returns valid verification", func() {
+				Expect(e).To(BeNil())
+				Expect(res.Valid).To(BeTrue())
+				Expect(res.Events).To(Equal(2))
+				Expect(res.BrokenAt).To(BeNil())
+			})
+		})
+
+		Context("legacy events", func() {
+			BeforeEach(func() {
+				legacy := events[0]
+				legacy.ID = generateID()
+				legacy.Sequence = 0
+				legacy.Hash = ""
+
+				verify(addRows(
+					mock.NewRows(chainColumns),
+					head,
+					append([]models.Event{legacy}, events...)))
+			})
+
+			It("counts events before the chain as unchained", func() {
+				Expect(res.Valid).To(BeTrue())
+				Expect(res.Events).To(Equal(3))
+				Expect(res.Unchained).To(Equal(1))
+			})
+		})
+
+		Context("hashes stripped from chained events", func() {
+			BeforeEach(func() {
+				for index := range events {
+					events[index].PreviousHash = ""
+					events[index].Hash = ""
+				}
+
+				verify(addRows(mock.NewRows(chainColumns), "", events))
+			})
+
+			It("returns missing hash", func() {
+				Expect(res.Valid).To(BeFalse())
+				Expect(res.Unchained).To(BeZero())
+				Expect(res.BrokenAt.EventID).To(Equal(events[0].ID))
+				Expect(res.BrokenAt.Reason).To(Equal(ChainMissingHash))
+			})
+		})
+
+		Context("modified event", func() {
+			BeforeEach(func() {
+				events[1].Enabled = true
+				verify(addRows(mock.NewRows(chainColumns), head, events))
+			})
+
+			It("returns hash mismatch", func() {
+				Expect(res.Valid).To(BeFalse())
+				Expect(res.BrokenAt.EventID).To(Equal(events[1].ID))
+				Expect(res.BrokenAt.Reason).To(Equal(ChainHashMismatch))
+			})
+		})
+
+		Context("deleted event", func() {
+			BeforeEach(func() {
+				verify(addRows(mock.NewRows(chainColumns), head, events[1:]))
+			})
+
+			It("returns previous hash mismatch", func() {
+				Expect(res.Valid).To(BeFalse())
+				Expect(res.BrokenAt.Sequence).To(Equal(int64(2)))
+				Expect(res.BrokenAt.Reason).To(Equal(ChainPreviousHashMismatch))
+			})
+		})
+
+		Context("purged event", func() {
+			BeforeEach(func() {
+				rows := mock.NewRows(chainColumns).AddRow(
+					userID,
+					head,
+					chainStart,
+					events[0].ID,
+					nil,
+					events[0].Sequence,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+					events[0].Hash,
+					true)
+
+				verify(addRows(rows, head, events[1:]))
+			})
+
+			It("links remaining events through the tombstone", func() {
+				Expect(res.Valid).To(BeTrue())
+				Expect(res.Events).To(Equal(1))
+				Expect(res.Purged).To(Equal(1))
+			})
+		})
+
+		Context("truncated chain", func() {
+			BeforeEach(func() {
+				verify(addRows(mock.NewRows(chainColumns), head, events[:1]))
+			})
+
+			It("returns head mismatch", func() {
+				Expect(res.Valid).To(BeFalse())
+				Expect(res.BrokenAt.Reason).To(Equal(ChainHeadMismatch))
+			})
+		})
+
+		Context("user without events", func() {
+			BeforeEach(func() {
+				verify(mock.NewRows(chainColumns).AddRow(
+					userID,
+					nil,
+					chainStart,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil,
+					nil))
+			})
+
+			It("returns valid verification", func() {
+				Expect(res.Valid).To(BeTrue())
+				Expect(res.Events).To(BeZero())
+			})
+		})
+
+		Context("non-existent user", func() {
+			BeforeEach(func() {
+				verify(mock.NewRows(chainColumns))
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("VerifyChains", func() {
+		var res *models.ChainReport
+
+		Context("broken chain", func() {
+			BeforeEach(func() {
+				events[0].Enabled = false
+				other := newEvents(
+					generateID(),
+					[]models.Consent{{ID: models.ConsentEmail, Enabled: true}},
+					1,
+					"",
+					time.Now().UTC().Truncate(time.Microsecond))
+				chainEvents(other, map[string]string{other[0].UserID: ""})
+
+				rows := addRows(mock.NewRows(chainColumns), head, events)
+				rows = addRows(rows, other[0].Hash, other)
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("ORDER BY u.id, e.sequence").
+					WillReturnRows(rows)
+				mock.ExpectCommit()
+
+				res, _ = event.VerifyChains(context.TODO())
+			})
+
+			It("stops at first broken user", func() {
+				Expect(res.Valid).To(BeFalse())
+				Expect(res.Users).To(Equal(1))
+				Expect(res.Broken.UserID).To(Equal(userID))
+				Expect(res.Broken.BrokenAt.Reason).To(Equal(ChainHashMismatch))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+	})
+})
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -42,37 +44,199 @@ var _ = Describe("Event", func() {
 			}
 
 			db, mock = NewSQLMock()
-			event = NewEvent(db)
+			event = NewEvent(db, nil)
 		})
 
 		Context("success", func() {
+			var res *models.EventCreateResult
 			var e error
 
 			BeforeEach(func() {
-				mock.ExpectBegin()
+				expectTenantTx(mock)
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 2).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(2, "+14155550100", nil))
+				mock.ExpectExec("INSERT INTO \"events\"(.+) VALUES \\(.+\\), \\(.+\\)$").
+					WithArgs(
+						sqlmock.AnyArg(),
+						userID,
+						models.ConsentEmail,
+						int64(1),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						true,
+						nil,
+						nil,
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						userID,
+						models.ConsentSMS,
+						int64(2),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						false,
+						"+14155550100",
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, true, nil, time.Now()).
+						AddRow(generateID(), models.ConsentSMS, false, nil, time.Now()))
+				mock.ExpectCommit()
+
+				res, e = event.Create(context.TODO(), req)
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+			})
+
+			It("returns created events", func() {
+				Expect(res.Events).To(HaveLen(2))
+				Expect(res.Events[0].ID).NotTo(BeEmpty())
+				Expect(res.Events[0].UserID).To(Equal(userID))
+				Expect(res.Events[0].ConsentID).To(Equal(models.ConsentEmail))
+				Expect(res.Events[1].Enabled).To(BeFalse())
+				Expect(res.Events[1].CreatedAt).NotTo(BeZero())
+			})
+
+			It("scopes sms consent to primary phone number", func() {
+				Expect(res.Events[0].PhoneNumber).To(BeEmpty())
+				Expect(res.Events[1].PhoneNumber).To(Equal("+14155550100"))
+			})
+
+			It("assigns consecutive sequence numbers", func() {
+				Expect(res.Events[0].Sequence).To(Equal(int64(1)))
+				Expect(res.Events[1].Sequence).To(Equal(int64(2)))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+
+			It("returns resulting consent state", func() {
+				Expect(res.Consents).To(HaveLen(2))
+				Expect(res.Version).NotTo(BeEmpty())
+			})
+
+			It("reports every consent as changed", func() {
+				Expect(res.Changed).To(Equal([]string{
+					models.ConsentEmail,
+					models.ConsentSMS,
+				}))
+			})
+		})
+
+		Context("client supplied occurred at", func() {
+			var (
+				occurredAt time.Time
+				newerAt    time.Time
+				res        *models.EventCreateResult
+			)
+
+			BeforeEach(func() {
+				occurredAt = time.Now().Add(-3 * time.Hour).UTC().Truncate(time.Microsecond)
+				newerAt = occurredAt.Add(time.Hour)
+				req.Consents = &[]models.Consent{
+					{
+						ID:         models.ConsentEmail,
+						Enabled:    false,
+						OccurredAt: &occurredAt,
+					},
+				}
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 1).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(1, nil, nil))
 				mock.ExpectExec("INSERT INTO \"events\"").
 					WithArgs(
 						sqlmock.AnyArg(),
 						userID,
 						models.ConsentEmail,
+						int64(1),
 						sqlmock.AnyArg(),
-						true).
+						occurredAt.Format(time.RFC3339Nano),
+						false,
+						nil,
+						nil,
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("ORDER BY occurred_at DESC, sequence DESC").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, true, nil, newerAt))
+				mock.ExpectCommit()
+
+				res, _ = event.Create(context.TODO(), req)
+			})
+
+			It("stores occurred at separately from received time", func() {
+				Expect(res.Events[0].OccurredAt).To(Equal(occurredAt))
+				Expect(res.Events[0].CreatedAt).To(BeTemporally(">", occurredAt))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+
+			It("returns the newer event as current state", func() {
+				Expect(res.States).To(Equal([]models.ConsentState{
+					{ID: models.ConsentEmail, Enabled: true, ChangedAt: newerAt},
+				}))
+				Expect(res.Consents).To(Equal([]models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				}))
+			})
+		})
+
+		Context("skip unchanged", func() {
+			var res *models.EventCreateResult
+
+			BeforeEach(func() {
+				req.SkipUnchanged = true
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT id FROM \"users\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id"}).AddRow(userID))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, true, nil, time.Now()).
+						AddRow(generateID(), models.ConsentSMS, true, nil, time.Now()))
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 1).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(1, nil, nil))
 				mock.ExpectExec("INSERT INTO \"events\"").
 					WithArgs(
 						sqlmock.AnyArg(),
 						userID,
 						models.ConsentSMS,
+						int64(1),
 						sqlmock.AnyArg(),
-						false).
+						sqlmock.AnyArg(),
+						false,
+						nil,
+						nil,
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
 				mock.ExpectCommit()
 
-				e = event.Create(context.TODO(), req)
+				res, _ = event.Create(context.TODO(), req)
 			})
 
-			It("does not return any error", func() {
-				Expect(e).To(BeNil())
+			It("writes only consents that change", func() {
+				Expect(res.Events).To(HaveLen(1))
+				Expect(res.Changed).To(Equal([]string{models.ConsentSMS}))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
 			})
 		})
 
@@ -82,7 +246,7 @@ var _ = Describe("Event", func() {
 			BeforeEach(func() {
 				mock.ExpectBegin().WillReturnError(fmt.Errorf("begin error"))
 
-				e = event.Create(context.TODO(), req)
+				_, e = event.Create(context.TODO(), req)
 			})
 
 			It("returns error", func() {
@@ -94,23 +258,382 @@ var _ = Describe("Event", func() {
 			var e error
 
 			BeforeEach(func() {
-				mock.ExpectBegin()
+				expectTenantTx(mock)
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 2).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(2, nil, nil))
 				mock.ExpectExec("INSERT INTO \"events\"").
-					WithArgs(
-						sqlmock.AnyArg(),
-						userID,
-						models.ConsentEmail,
-						sqlmock.AnyArg(),
-						true).
 					WillReturnError(fmt.Errorf("insert error"))
 				mock.ExpectRollback()
 
-				e = event.Create(context.TODO(), req)
+				_, e = event.Create(context.TODO(), req)
 			})
 
 			It("returns error", func() {
 				Expect(e).NotTo(BeNil())
 			})
 		})
+
+		Context("consent not offered by tenant", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 2).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(2, nil, nil))
+				mock.ExpectExec("INSERT INTO \"events\"").
+					WillReturnError(&pq.Error{
+						Code:       pqForeignKeyViolation,
+						Constraint: "events_tenant_consents",
+					})
+				mock.ExpectRollback()
+
+				_, e = event.Create(context.TODO(), req)
+			})
+
+			It("returns consent not offered error", func() {
+				Expect(e).To(MatchError(ErrConsentNotOffered))
+			})
+		})
+
+		Context("matching precondition", func() {
+			var e error
+
+			BeforeEach(func() {
+				eventID := generateID()
+				req.IfMatch = []string{
+					userVersion([]string{generateID()}, mockProfile),
+					userVersion([]string{eventID}, mockProfile),
+				}
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT id FROM \"users\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id"}).AddRow(userID))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(eventID, models.ConsentEmail, false, nil, time.Now()))
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 2).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(2, nil, nil))
+				mock.ExpectExec("INSERT INTO \"events\"").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectCommit()
+
+				_, e = event.Create(context.TODO(), req)
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("stale precondition", func() {
+			var e error
+
+			BeforeEach(func() {
+				req.IfMatch = []string{userVersion([]string{generateID()}, mockProfile)}
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT id FROM \"users\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id"}).AddRow(userID))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, false, nil, time.Now()))
+				mock.ExpectRollback()
+
+				_, e = event.Create(context.TODO(), req)
+			})
+
+			It("returns precondition failed error", func() {
+				Expect(e).To(MatchError(ErrPreconditionFailed))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("weak precondition", func() {
+			var e error
+
+			BeforeEach(func() {
+				eventID := generateID()
+				req.IfMatch = []string{
+					`W/"` + userVersion([]string{eventID}, mockProfile) + `"`,
+				}
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT id FROM \"users\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id"}).AddRow(userID))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(userID).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(eventID, models.ConsentEmail, false, nil, time.Now()))
+				mock.ExpectRollback()
+
+				_, e = event.Create(context.TODO(), req)
+			})
+
+			It("returns precondition failed error", func() {
+				Expect(e).To(MatchError(ErrPreconditionFailed))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("sms enabled without primary phone", func() {
+			var e error
+
+			BeforeEach(func() {
+				(*req.Consents)[1].Enabled = true
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 2).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(2, nil, nil))
+				mock.ExpectRollback()
+
+				_, e = event.Create(context.TODO(), req)
+			})
+
+			It("returns phone required error", func() {
+				Expect(e).To(MatchError(ErrPhoneRequired))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("non-existent user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(userID, 2).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = event.Create(context.TODO(), req)
+			})
+
+			It("returns user not found error", func() {
+				Expect(e).To(MatchError(ErrUserNotFound))
+			})
+		})
+
+		Context("new device", func() {
+			var (
+				res *models.EventCreateResult
+				e   error
+			)
+
+			BeforeEach(func() {
+				req.User = nil
+				req.Device = &models.EventCreateDevice{ID: "cookie-4f1c"}
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT user_id FROM \"devices\"").
+					WithArgs("cookie-4f1c").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"devices\"").
+					WithArgs("cookie-4f1c", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(sqlmock.AnyArg(), 2).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(2, nil, nil))
+				mock.ExpectExec("INSERT INTO \"events\"").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, true, nil, time.Now()).
+						AddRow(generateID(), models.ConsentSMS, false, nil, time.Now()))
+				mock.ExpectCommit()
+
+				res, e = event.Create(context.TODO(), req)
+			})
+
+			It("records events against anonymous user of device", func() {
+				Expect(e).To(BeNil())
+				Expect(res.Events).To(HaveLen(2))
+				Expect(res.Events[0].UserID).NotTo(BeEmpty())
+				Expect(res.Events[1].UserID).To(Equal(res.Events[0].UserID))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Detail", func() {
+		var (
+			id string
+
+			db    *sql.DB
+			mock  sqlmock.Sqlmock
+			event Event
+		)
+
+		BeforeEach(func() {
+			id = generateID()
+
+			db, mock = NewSQLMock()
+			event = NewEvent(db, nil)
+		})
+
+		Context("existent", func() {
+			var res *models.Event
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"user_id",
+						"consent_id",
+						"sequence",
+						"enabled",
+						"phone_number",
+						"occurred_at",
+						"created_at",
+						"previous_hash",
+						"hash"}).
+						AddRow(
+							id,
+							generateID(),
+							models.ConsentSMS,
+							7,
+							true,
+							"+14155550100",
+							time.Now(),
+							time.Now(),
+							nil,
+							nil))
+				mock.ExpectCommit()
+
+				res, _ = event.Detail(context.TODO(), id)
+			})
+
+			It("returns matching event", func() {
+				Expect(res).NotTo(BeNil())
+				Expect(res.ID).To(Equal(id))
+				Expect(res.ConsentID).To(Equal(models.ConsentSMS))
+				Expect(res.Sequence).To(Equal(int64(7)))
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = event.Detail(context.TODO(), id)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Consent", func() {
+		var (
+			userID string
+
+			db    *sql.DB
+			mock  sqlmock.Sqlmock
+			event Event
+		)
+
+		BeforeEach(func() {
+			userID = generateID()
+
+			db, mock = NewSQLMock()
+			event = NewEvent(db, nil)
+		})
+
+		Context("existent", func() {
+			var (
+				changedAt time.Time
+				res       *models.ConsentState
+				e         error
+			)
+
+			BeforeEach(func() {
+				changedAt = time.Now().UTC()
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(userID, models.ConsentEmail).
+					WillReturnRows(mock.NewRows([]string{"enabled", "phone_number", "created_at"}).
+						AddRow(true, nil, changedAt))
+				mock.ExpectCommit()
+
+				res, e = event.Consent(context.TODO(), userID, models.ConsentEmail)
+			})
+
+			It("returns latest consent state", func() {
+				Expect(e).To(BeNil())
+				Expect(res.ID).To(Equal(models.ConsentEmail))
+				Expect(res.Enabled).To(BeTrue())
+				Expect(res.ChangedAt).To(Equal(changedAt))
+			})
+		})
+
+		Context("never set", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(userID, models.ConsentEmail).
+					WillReturnRows(mock.NewRows([]string{"enabled", "phone_number", "created_at"}).
+						AddRow(nil, nil, nil))
+				mock.ExpectCommit()
+
+				_, e = event.Consent(context.TODO(), userID, models.ConsentEmail)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+
+		Context("non-existent user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(userID, models.ConsentEmail).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = event.Consent(context.TODO(), userID, models.ConsentEmail)
+			})
+
+			It("returns user not found error", func() {
+				Expect(e).To(MatchError(ErrUserNotFound))
+			})
+		})
 	})
 })
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+)
+
+const SchemaVersion = 16
+
+type Health interface {
+	Ping(ctx context.Context) error
+
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+type PostgresHealth struct {
+	db *sql.DB
+}
+
+func NewHealth(db *sql.DB) Health {
+	return &PostgresHealth{db}
+}
+
+func (h *PostgresHealth) Ping(ctx context.Context) error {
+	return h.db.PingContext(ctx)
+}
+
+func (h *PostgresHealth) SchemaVersion(ctx context.Context) (int, error) {
+	const query = `SELECT COALESCE(MAX(version), 0) FROM "schema_migrations"`
+
+	var version int
+
+	if err := h.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
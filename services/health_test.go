@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Health", func() {
+	var (
+		db     *sql.DB
+		mock   sqlmock.Sqlmock
+		health Health
+	)
+
+	BeforeEach(func() {
+		db, mock = NewSQLMock()
+		health = NewHealth(db)
+	})
+
+	Describe("SchemaVersion", func() {
+		Context("success", func() {
+			var version int
+			var e error
+
+			BeforeEach(func() {
+				mock.ExpectQuery("FROM \"schema_migrations\"").
+					WillReturnRows(mock.NewRows([]string{"version"}).AddRow(1))
+
+				version, e = health.SchemaVersion(context.TODO())
+			})
+
+			It("returns current version", func() {
+				Expect(e).To(BeNil())
+				Expect(version).To(Equal(1))
+			})
+		})
+
+		Context("error querying", func() {
+			var e error
+
+			BeforeEach(func() {
+				mock.ExpectQuery("FROM \"schema_migrations\"").
+					WillReturnError(fmt.Errorf("query error"))
+
+				_, e = health.SchemaVersion(context.TODO())
+			})
+
+			It("returns error", func() {
+				Expect(e).NotTo(BeNil())
+			})
+		})
+	})
+})
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+const (
+	receiptCollectionMethod = "api"
+	receiptLanguage         = "en"
+	receiptService          = "consents"
+	receiptConsentType      = "EXPLICIT"
+	receiptTermination      = "Until withdrawn by the PII principal"
+)
+
+const receiptColumns = `id, user_id, key_id, receipt, jws, created_at`
+
+var receiptPIICategories = map[string][]string{
+	models.ConsentEmail: {"email address"},
+	models.ConsentSMS:   {"phone number"},
+}
+
+type Receipt interface {
+	Detail(ctx context.Context, id string) (*models.Receipt, error)
+
+	Keys() *models.JWKSet
+}
+
+type PostgresReceipt struct {
+	db      *sql.DB
+	signer  *ReceiptSigner
+	retired []models.JWK
+}
+
+func NewReceipt(
+	db *sql.DB,
+	signer *ReceiptSigner,
+	retired ...models.JWK) Receipt {
+	return &PostgresReceipt{db, signer, retired}
+}
+
+func (r *PostgresReceipt) Detail(
+	ctx context.Context,
+	id string) (*models.Receipt, error) {
+	const query = `SELECT ` + receiptColumns + ` FROM "receipts" WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresReceipt.Detail")
+	defer span.End()
+
+	var receipt *models.Receipt
+
+	if err := inTenant(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+
+		receipt, err = scanReceipt(tx.QueryRowContext(ctx, query, id).Scan)
+
+		return err
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return receipt, nil
+}
+
+func (r *PostgresReceipt) Keys() *models.JWKSet {
+	keys := make([]models.JWK, 0, len(r.retired)+1)
+
+	if r.signer != nil {
+		keys = append(keys, r.signer.JWK())
+	}
+
+	for _, key := range r.retired {
+		if r.signer == nil || key.KeyID != r.signer.KeyID() {
+			keys = append(keys, key)
+		}
+	}
+
+	return &models.JWKSet{Keys: keys}
+}
+
+type ReceiptIssuer struct {
+	Signer       *ReceiptSigner
+	Jurisdiction string
+	PolicyURL    string
+	Controller   models.ReceiptController
+}
+
+func (i *ReceiptIssuer) issue(
+	ctx context.Context,
+	tx *sql.Tx,
+	events []models.Event) (string, error) {
+	const query = `INSERT INTO "receipts"(id, user_id, key_id, receipt, jws, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	ctx, span := tracing.Start(ctx, "issue receipt")
+	defer span.End()
+
+	purposes := make([]models.ReceiptPurpose, 0, len(events))
+
+	for _, event := range events {
+		categories, ok := receiptPIICategories[event.ConsentID]
+
+		if !ok {
+			categories = []string{}
+		}
+
+		purposes = append(purposes, models.ReceiptPurpose{
+			Purpose:         event.ConsentID,
+			PurposeCategory: []string{event.ConsentID},
+			ConsentType:     receiptConsentType,
+			PIICategory:     categories,
+			PrimaryPurpose:  true,
+			Termination:     receiptTermination,
+			Enabled:         event.Enabled,
+			EventID:         event.ID,
+		})
+	}
+
+	id := generateID()
+	createdAt := events[0].CreatedAt
+	consentedAt := events[0].OccurredAt
+
+	for _, event := range events[1:] {
+		if event.OccurredAt.After(consentedAt) {
+			consentedAt = event.OccurredAt
+		}
+	}
+
+	receipt := models.ConsentReceipt{
+		Version:          models.ConsentReceiptVersion,
+		Jurisdiction:     i.Jurisdiction,
+		ConsentTimestamp: consentedAt.Unix(),
+		CollectionMethod: receiptCollectionMethod,
+		ConsentReceiptID: id,
+		PublicKey:        i.Signer.PublicKey(),
+		Language:         receiptLanguage,
+		PIIPrincipalID:   events[0].UserID,
+		PIIControllers:   []models.ReceiptController{i.Controller},
+		PolicyURL:        i.PolicyURL,
+		Services: []models.ReceiptService{
+			{Service: receiptService, Purposes: purposes},
+		},
+		SPICat: []string{},
+	}
+
+	content, err := json.Marshal(receipt)
+
+	if err != nil {
+		return "", err
+	}
+
+	jws, err := i.Signer.Sign(content)
+
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		query,
+		id,
+		receipt.PIIPrincipalID,
+		i.Signer.KeyID(),
+		content,
+		jws,
+		createdAt); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (i *ReceiptIssuer) issueEach(
+	ctx context.Context,
+	tx *sql.Tx,
+	events []models.Event) (map[string]string, error) {
+	grouped := make(map[string][]models.Event)
+
+	for _, event := range events {
+		grouped[event.UserID] = append(grouped[event.UserID], event)
+	}
+
+	receiptIDs := make(map[string]string, len(grouped))
+
+	for _, userID := range sortedKeys(grouped) {
+		id, err := i.issue(ctx, tx, grouped[userID])
+
+		if err != nil {
+			return nil, err
+		}
+
+		receiptIDs[userID] = id
+	}
+
+	return receiptIDs, nil
+}
+
+func userReceipts(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.Receipt, error) {
+	const query = `SELECT ` + receiptColumns + ` FROM "receipts" WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	receipts := make([]models.Receipt, 0)
+
+	for rows.Next() {
+		receipt, err := scanReceipt(rows.Scan)
+
+		if err != nil {
+			return nil, err
+		}
+
+		receipts = append(receipts, *receipt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return receipts, nil
+}
+
+func scanReceipt(scan func(dest ...interface{}) error) (*models.Receipt, error) {
+	var (
+		receipt models.Receipt
+		content []byte
+	)
+
+	if err := scan(
+		&receipt.ID,
+		&receipt.UserID,
+		&receipt.KeyID,
+		&content,
+		&receipt.JWS,
+		&receipt.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &receipt.Receipt); err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
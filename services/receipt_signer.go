@@ -0,0 +1,219 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+type ReceiptSigner struct {
+	key       crypto.Signer
+	jwk       models.JWK
+	publicKey string
+}
+
+func NewReceiptSigner(encoded []byte) (*ReceiptSigner, error) {
+	key, err := parseReceiptKey(encoded)
+
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := key.(crypto.Signer)
+
+	if !ok {
+		return nil, ErrUnsupportedSigningKey
+	}
+
+	jwk, err := receiptJWK(private.Public())
+
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(private.Public())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReceiptSigner{
+		key:       private,
+		jwk:       jwk,
+		publicKey: base64.StdEncoding.EncodeToString(public),
+	}, nil
+}
+
+func NewReceiptVerificationKey(encoded []byte) (models.JWK, error) {
+	key, err := parseReceiptKey(encoded)
+
+	if err != nil {
+		return models.JWK{}, err
+	}
+
+	if private, ok := key.(crypto.Signer); ok {
+		key = private.Public()
+	}
+
+	return receiptJWK(key)
+}
+
+func parseReceiptKey(encoded []byte) (interface{}, error) {
+	block, _ := pem.Decode(encoded)
+
+	if block == nil {
+		return nil, ErrUnsupportedSigningKey
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedSigningKey, err)
+	}
+
+	return key, nil
+}
+
+func receiptJWK(key crypto.PublicKey) (models.JWK, error) {
+	var jwk models.JWK
+
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		jwk = models.JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         encodeSegment(key),
+			Algorithm: "EdDSA",
+		}
+	case *ecdsa.PublicKey:
+		var algorithm string
+
+		switch key.Curve {
+		case elliptic.P256():
+			algorithm = "ES256"
+		case elliptic.P384():
+			algorithm = "ES384"
+		default:
+			return models.JWK{}, ErrUnsupportedSigningKey
+		}
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk = models.JWK{
+			KeyType:   "EC",
+			Curve:     key.Curve.Params().Name,
+			X:         encodeSegment(key.X.FillBytes(make([]byte, size))),
+			Y:         encodeSegment(key.Y.FillBytes(make([]byte, size))),
+			Algorithm: algorithm,
+		}
+	default:
+		return models.JWK{}, ErrUnsupportedSigningKey
+	}
+
+	jwk.Use = "sig"
+	jwk.KeyID = thumbprint(jwk)
+
+	return jwk, nil
+}
+
+func (s *ReceiptSigner) KeyID() string {
+	return s.jwk.KeyID
+}
+
+func (s *ReceiptSigner) JWK() models.JWK {
+	return s.jwk
+}
+
+func (s *ReceiptSigner) PublicKey() string {
+	return s.publicKey
+}
+
+func (s *ReceiptSigner) Sign(payload []byte) (string, error) {
+	header, err := json.Marshal(struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+		Type      string `json:"typ"`
+	}{s.jwk.Algorithm, s.jwk.KeyID, "JWT"})
+
+	if err != nil {
+		return "", err
+	}
+
+	input := encodeSegment(header) + "." + encodeSegment(payload)
+	var signature []byte
+
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(input))
+	case *ecdsa.PrivateKey:
+		var digest hash.Hash = sha256.New()
+
+		if s.jwk.Algorithm == "ES384" {
+			digest = sha512.New384()
+		}
+
+		digest.Write([]byte(input))
+		r, sig, err := ecdsa.Sign(rand.Reader, key, digest.Sum(nil))
+
+		if err != nil {
+			return "", err
+		}
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = append(
+			r.FillBytes(make([]byte, size)),
+			sig.FillBytes(make([]byte, size))...)
+	}
+
+	return input + "." + encodeSegment(signature), nil
+}
+
+func thumbprint(jwk models.JWK) string {
+	var members string
+
+	if jwk.KeyType == "OKP" {
+		members = fmt.Sprintf(
+			`{"crv":%q,"kty":%q,"x":%q}`,
+			jwk.Curve,
+			jwk.KeyType,
+			jwk.X)
+	} else {
+		members = fmt.Sprintf(
+			`{"crv":%q,"kty":%q,"x":%q,"y":%q}`,
+			jwk.Curve,
+			jwk.KeyType,
+			jwk.X,
+			jwk.Y)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+
+	return encodeSegment(sum[:])
+}
+
+func encodeSegment(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
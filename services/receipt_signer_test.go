@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReceiptSigner", func() {
+	encodeKey := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+
+		if err != nil {
+			panic(err)
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	decodeSegment := func(segment string) []byte {
+		value, err := base64.RawURLEncoding.DecodeString(segment)
+
+		if err != nil {
+			panic(err)
+		}
+
+		return value
+	}
+
+	payload := []byte(`{"version":"KI-CR-v1.1.0"}`)
+
+	Context("ed25519 key", func() {
+		var (
+			public ed25519.PublicKey
+			signer *ReceiptSigner
+			jws    string
+		)
+
+		BeforeEach(func() {
+			var (
+				private ed25519.PrivateKey
+				err     error
+			)
+
+			public, private, err = ed25519.GenerateKey(rand.Reader)
+
+			if err != nil {
+				panic(err)
+			}
+
+			signer, err = NewReceiptSigner(encodeKey(private))
+
+			if err != nil {
+				panic(err)
+			}
+
+			jws, err = signer.Sign(payload)
+
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("publishes key as jwk", func() {
+			Expect(signer.JWK().KeyType).To(Equal("OKP"))
+			Expect(signer.JWK().Algorithm).To(Equal("EdDSA"))
+			Expect(decodeSegment(signer.JWK().X)).To(Equal([]byte(public)))
+		})
+
+		It("signs payload as compact jws", func() {
+			segments := strings.Split(jws, ".")
+
+			var header map[string]string
+
+			Expect(json.Unmarshal(decodeSegment(segments[0]), &header)).To(Succeed())
+			Expect(header["alg"]).To(Equal("EdDSA"))
+			Expect(header["kid"]).To(Equal(signer.KeyID()))
+			Expect(decodeSegment(segments[1])).To(Equal(payload))
+			Expect(ed25519.Verify(
+				public,
+				[]byte(segments[0]+"."+segments[1]),
+				decodeSegment(segments[2]))).To(BeTrue())
+		})
+	})
+
+	Context("p-256 key", func() {
+		var (
+			private *ecdsa.PrivateKey
+			signer  *ReceiptSigner
+			jws     string
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+			if err != nil {
+				panic(err)
+			}
+
+			signer, err = NewReceiptSigner(encodeKey(private))
+
+			if err != nil {
+				panic(err)
+			}
+
+			jws, err = signer.Sign(payload)
+
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("signs payload with es256", func() {
+			segments := strings.Split(jws, ".")
+			signature := decodeSegment(segments[2])
+			digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+
+			Expect(signer.JWK().Algorithm).To(Equal("ES256"))
+			Expect(signature).To(HaveLen(64))
+			Expect(ecdsa.Verify(
+				&private.PublicKey,
+				digest[:],
+				new(big.Int).SetBytes(signature[:32]),
+				new(big.Int).SetBytes(signature[32:]))).To(BeTrue())
+		})
+	})
+
+	Context("rsa key", func() {
+		var e error
+
+		BeforeEach(func() {
+			private, err := rsa.GenerateKey(rand.Reader, 2048)
+
+			if err != nil {
+				panic(err)
+			}
+
+			_, e = NewReceiptSigner(encodeKey(private))
+		})
+
+		It("returns unsupported signing key error", func() {
+			Expect(e).To(MatchError(ErrUnsupportedSigningKey))
+		})
+	})
+
+	Context("malformed key", func() {
+		It("returns unsupported signing key error", func() {
+			_, e := NewReceiptSigner([]byte("not a key"))
+
+			Expect(e).To(MatchError(ErrUnsupportedSigningKey))
+		})
+	})
+})
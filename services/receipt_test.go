@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/pem"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(value driver.Value) bool {
+	a.value = value
+	return true
+}
+
+var _ = Describe("Receipt", func() {
+	var (
+		db      *sql.DB
+		mock    sqlmock.Sqlmock
+		signer  *ReceiptSigner
+		receipt Receipt
+	)
+
+	BeforeEach(func() {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+
+		if err != nil {
+			panic(err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+
+		if err != nil {
+			panic(err)
+		}
+
+		signer, err = NewReceiptSigner(pem.EncodeToMemory(
+			&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+		if err != nil {
+			panic(err)
+		}
+
+		db, mock = NewSQLMock()
+		receipt = NewReceipt(db, signer)
+	})
+
+	Describe("Detail", func() {
+		var id string
+
+		BeforeEach(func() {
+			id = generateID()
+		})
+
+		Context("existent", func() {
+			var res *models.Receipt
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"receipts\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"user_id",
+						"key_id",
+						"receipt",
+						"jws",
+						"created_at"}).
+						AddRow(
+							id,
+							generateID(),
+							signer.KeyID(),
+							[]byte(`{"version":"KI-CR-v1.1.0","consentReceiptID":"`+id+`"}`),
+							"header.payload.signature",
+							time.Now()))
+				mock.ExpectCommit()
+
+				res, _ = receipt.Detail(context.TODO(), id)
+			})
+
+			It("returns stored receipt", func() {
+				Expect(res.ID).To(Equal(id))
+				Expect(res.KeyID).To(Equal(signer.KeyID()))
+				Expect(res.Receipt.Version).To(Equal(models.ConsentReceiptVersion))
+				Expect(res.Receipt.ConsentReceiptID).To(Equal(id))
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"receipts\"").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = receipt.Detail(context.TODO(), id)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Keys", func() {
+		It("returns key of signer", func() {
+			Expect(receipt.Keys().Keys).To(Equal([]models.JWK{signer.JWK()}))
+		})
+
+		It("returns no key without signer", func() {
+			Expect(NewReceipt(db, nil).Keys().Keys).To(BeEmpty())
+		})
+
+		It("returns retired keys after key of signer", func() {
+			public, _, err := ed25519.GenerateKey(rand.Reader)
+
+			if err != nil {
+				panic(err)
+			}
+
+			der, err := x509.MarshalPKIXPublicKey(public)
+
+			if err != nil {
+				panic(err)
+			}
+
+			retired, err := NewReceiptVerificationKey(pem.EncodeToMemory(
+				&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+			Expect(err).To(BeNil())
+			Expect(NewReceipt(db, signer, retired, signer.JWK()).Keys().Keys).
+				To(Equal([]models.JWK{signer.JWK(), retired}))
+		})
+	})
+
+	Describe("issued on event create", func() {
+		var (
+			userID     string
+			occurredAt time.Time
+			content    *capturedArg
+			jws        *capturedArg
+			res        *models.EventCreateResult
+			e          error
+		)
+
+		BeforeEach(func() {
+			userID = generateID()
+			occurredAt = time.Date(2026, time.September, 30, 8, 0, 0, 0, time.UTC)
+			content = &capturedArg{}
+			jws = &capturedArg{}
+
+			event := NewEvent(db, &ReceiptIssuer{
+				Signer:       signer,
+				Jurisdiction: "US",
+				PolicyURL:    "https://example.com/privacy",
+				Controller: models.ReceiptController{
+					PIIController: "Example Inc.",
+				},
+			})
+
+			expectTenantTx(mock)
+			mock.ExpectQuery("UPDATE \"users\"").
+				WithArgs(userID, 1).
+				WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).AddRow(1, nil, nil))
+			mock.ExpectExec("INSERT INTO \"events\"").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("INSERT INTO \"receipts\"").
+				WithArgs(
+					sqlmock.AnyArg(),
+					userID,
+					signer.KeyID(),
+					content,
+					jws,
+					sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			expectProfile(mock)
+			mock.ExpectQuery("FROM \"events\"").
+				WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+			mock.ExpectCommit()
+
+			res, e = event.Create(context.TODO(), &models.EventCreateRequest{
+				User: &models.EventCreateUser{ID: userID},
+				Consents: &[]models.Consent{
+					{ID: models.ConsentEmail, Enabled: false, OccurredAt: &occurredAt},
+				},
+			})
+		})
+
+		It("returns receipt id", func() {
+			Expect(e).To(BeNil())
+			Expect(res.ReceiptID).NotTo(BeEmpty())
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("stores kantara receipt of recorded events", func() {
+			var stored models.ConsentReceipt
+
+			Expect(json.Unmarshal(content.value.([]byte), &stored)).To(Succeed())
+			Expect(stored.ConsentReceiptID).To(Equal(res.ReceiptID))
+			Expect(stored.PIIPrincipalID).To(Equal(userID))
+			Expect(stored.Jurisdiction).To(Equal("US"))
+			Expect(stored.PublicKey).To(Equal(signer.PublicKey()))
+			Expect(stored.Services[0].Purposes).To(HaveLen(1))
+			Expect(stored.Services[0].Purposes[0].EventID).To(Equal(res.Events[0].ID))
+			Expect(stored.Services[0].Purposes[0].Enabled).To(BeFalse())
+		})
+
+		It("stamps receipt with time consent occurred", func() {
+			var stored models.ConsentReceipt
+
+			Expect(json.Unmarshal(content.value.([]byte), &stored)).To(Succeed())
+			Expect(stored.ConsentTimestamp).To(Equal(occurredAt.Unix()))
+		})
+
+		It("signs stored receipt", func() {
+			payload, err := json.Marshal(json.RawMessage(content.value.([]byte)))
+
+			Expect(err).To(BeNil())
+			Expect(jws.value).To(ContainSubstring("." + encodeSegment(payload) + "."))
+		})
+	})
+
+	Describe("issued on batch create", func() {
+		var (
+			firstUserID  string
+			secondUserID string
+			res          []models.EventBatchItemResult
+			e            error
+		)
+
+		BeforeEach(func() {
+			firstUserID = "00000000-0000-4000-8000-000000000001"
+			secondUserID = "00000000-0000-4000-8000-000000000002"
+
+			event := NewEvent(db, &ReceiptIssuer{Signer: signer})
+
+			expectTenantTx(mock)
+			mock.ExpectQuery("FOR UPDATE").
+				WillReturnRows(mock.NewRows([]string{"id", "event_sequence", "number", "event_hash"}).
+					AddRow(firstUserID, 0, nil, nil).
+					AddRow(secondUserID, 0, nil, nil))
+			expectOfferedConsents(mock, models.ConsentEmail, models.ConsentSMS)
+			mock.ExpectExec("UPDATE \"users\" u\\s+SET event_sequence").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec("INSERT INTO \"events\"").
+				WillReturnResult(sqlmock.NewResult(0, 3))
+			mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec("INSERT INTO \"receipts\"").
+				WithArgs(
+					sqlmock.AnyArg(),
+					firstUserID,
+					signer.KeyID(),
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("INSERT INTO \"receipts\"").
+				WithArgs(
+					sqlmock.AnyArg(),
+					secondUserID,
+					signer.KeyID(),
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			res, e = event.CreateBatch(context.TODO(), &models.EventBatchRequest{
+				Items: []models.EventCreateRequest{
+					{
+						User: &models.EventCreateUser{ID: firstUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: false},
+						},
+					},
+					{
+						User: &models.EventCreateUser{ID: secondUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentEmail, Enabled: true},
+						},
+					},
+					{
+						User: &models.EventCreateUser{ID: firstUserID},
+						Consents: &[]models.Consent{
+							{ID: models.ConsentSMS, Enabled: false},
+						},
+					},
+				},
+			})
+		})
+
+		It("issues one receipt per user", func() {
+			Expect(e).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("returns receipt of user with every item", func() {
+			Expect(res[0].ReceiptID).NotTo(BeEmpty())
+			Expect(res[2].ReceiptID).To(Equal(res[0].ReceiptID))
+			Expect(res[1].ReceiptID).NotTo(BeEmpty())
+			Expect(res[1].ReceiptID).NotTo(Equal(res[0].ReceiptID))
+		})
+	})
+
+	Describe("issued on user create", func() {
+		var (
+			res *models.User
+			e   error
+		)
+
+		BeforeEach(func() {
+			user := NewUser(db, &ReceiptIssuer{Signer: signer})
+
+			expectTenantTx(mock)
+			mock.ExpectExec("INSERT INTO \"users\"").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("INSERT INTO \"events\"").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("INSERT INTO \"receipts\"").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			expectProfile(mock)
+			mock.ExpectQuery("FROM \"events\"").
+				WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+			mock.ExpectCommit()
+
+			res, e = user.Create(context.TODO(), &models.UserCreateRequest{
+				Email: "user@example.com",
+				Consents: []models.Consent{
+					{ID: models.ConsentEmail, Enabled: true},
+				},
+			})
+		})
+
+		It("returns receipt id of initial consents", func() {
+			Expect(e).To(BeNil())
+			Expect(res.ReceiptID).NotTo(BeEmpty())
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
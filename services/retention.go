@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+const DefaultPurgeBatchSize = 1000
+
+const purgeCandidatesQuery = `
+SELECT o.id, o.consent_id
+FROM "events" o
+JOIN "tenant_consents" c ON c.tenant_id = o.tenant_id AND c.consent_id = o.consent_id
+JOIN "users" u ON u.id = o.user_id
+WHERE c.retention IS NOT NULL
+AND u.legal_hold_at IS NULL
+AND EXISTS(
+	SELECT 1
+	FROM "events" n
+	WHERE n.user_id = o.user_id
+	AND n.consent_id = o.consent_id
+	AND n.phone_number IS NOT DISTINCT FROM o.phone_number
+	AND (n.occurred_at, n.sequence) > (o.occurred_at, o.sequence)
+	AND n.occurred_at < now() - c.retention)`
+
+type PurgeOptions struct {
+	BatchSize int
+	DryRun    bool
+}
+
+type Retention interface {
+	Purge(ctx context.Context, options PurgeOptions) (*models.PurgeReport, error)
+}
+
+type PostgresRetention struct {
+	db *sql.DB
+}
+
+func NewRetention(db *sql.DB) Retention {
+	return &PostgresRetention{db}
+}
+
+func (r *PostgresRetention) Purge(
+	ctx context.Context,
+	options PurgeOptions) (*models.PurgeReport, error) {
+
+	ctx, span := tracing.Start(ctx, "PostgresRetention.Purge")
+	defer span.End()
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultPurgeBatchSize
+	}
+
+	tenants, err := r.tenants(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PurgeReport{
+		DryRun:  options.DryRun,
+		Tenants: make([]models.TenantPurge, 0, len(tenants)),
+	}
+
+	for _, tenantID := range tenants {
+		purge := models.TenantPurge{
+			TenantID: tenantID,
+			Consents: make(map[string]int64),
+		}
+
+		tenantCtx := WithTenant(ctx, tenantID)
+
+		if options.DryRun {
+			err = r.count(tenantCtx, &purge)
+		} else {
+			err = r.purge(tenantCtx, options.BatchSize, &purge)
+		}
+
+		if purge.Events > 0 {
+			report.Events += purge.Events
+			report.Tenants = append(report.Tenants, purge)
+		}
+
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func (r *PostgresRetention) tenants(ctx context.Context) ([]string, error) {
+	const query = `SELECT DISTINCT tenant_id FROM "tenant_consents" WHERE retention IS NOT NULL ORDER BY tenant_id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	tenants := make([]string, 0)
+
+	for rows.Next() {
+		var tenantID string
+
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+
+		tenants = append(tenants, tenantID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tenants, nil
+}
+
+func (r *PostgresRetention) count(
+	ctx context.Context,
+	purge *models.TenantPurge) error {
+
+	const query = `SELECT p.consent_id, count(*) FROM (` + purgeCandidatesQuery + `) p GROUP BY p.consent_id`
+
+	var counts map[string]int64
+
+	if err := inTenant(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+
+		counts, err = countPurged(ctx, tx, query)
+
+		return err
+	}); err != nil {
+		return err
+	}
+
+	addPurged(purge, counts)
+
+	return nil
+}
+
+func (r *PostgresRetention) purge(
+	ctx context.Context,
+	batchSize int,
+	purge *models.TenantPurge) error {
+
+	const query = `
+WITH batch AS (` + purgeCandidatesQuery + `
+	ORDER BY o.occurred_at
+	LIMIT $1
+	FOR UPDATE OF o SKIP LOCKED),
+purged AS (
+	DELETE FROM "events" e
+	USING batch
+	WHERE e.id = batch.id
+	RETURNING e.tenant_id, e.id, e.user_id, e.consent_id, e.sequence, e.previous_hash, e.hash, e.merged_from),
+tombstones AS (
+	INSERT INTO "event_tombstones"(tenant_id, event_id, user_id, sequence, previous_hash, hash, merged_from)
+	SELECT tenant_id, id, user_id, sequence, previous_hash, hash, merged_from
+	FROM purged)
+SELECT consent_id, count(*) FROM purged GROUP BY consent_id`
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var counts map[string]int64
+
+		if err := inTenant(ctx, r.db, func(tx *sql.Tx) error {
+			var err error
+
+			counts, err = countPurged(ctx, tx, query, batchSize)
+
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if addPurged(purge, counts) < int64(batchSize) {
+			return nil
+		}
+	}
+}
+
+func countPurged(
+	ctx context.Context,
+	q queryer,
+	query string,
+	values ...interface{}) (map[string]int64, error) {
+
+	rows, err := q.QueryContext(ctx, query, values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[string]int64)
+
+	for rows.Next() {
+		var (
+			consentID string
+			count     int64
+		)
+
+		if err := rows.Scan(&consentID, &count); err != nil {
+			return nil, err
+		}
+
+		counts[consentID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func addPurged(purge *models.TenantPurge, counts map[string]int64) int64 {
+	var total int64
+
+	for consentID, count := range counts {
+		purge.Consents[consentID] += count
+		total += count
+	}
+
+	purge.Events += total
+
+	return total
+}
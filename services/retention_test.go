@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("Retention", func() {
+	var (
+		db        *sql.DB
+		mock      sqlmock.Sqlmock
+		retention Retention
+	)
+
+	countColumns := []string{"consent_id", "count"}
+
+	BeforeEach(func() {
+		db, mock = NewSQLMock()
+		retention = NewRetention(db)
+	})
+
+	expectTenants := func(tenants ...string) {
+		rows := mock.NewRows([]string{"tenant_id"})
+
+		for _, tenant := range tenants {
+			rows.AddRow(tenant)
+		}
+
+		mock.ExpectQuery("FROM \"tenant_consents\" WHERE retention IS NOT NULL").
+			WillReturnRows(rows)
+	}
+
+	Describe("Purge", func() {
+		Context("dry run", func() {
+			var (
+				res *models.PurgeReport
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenants(DefaultTenant, "acme")
+				expectTenantTx(mock)
+				mock.ExpectQuery("GROUP BY p.consent_id").
+					WillReturnRows(mock.NewRows(countColumns).
+						AddRow(models.ConsentEmail, 3).
+						AddRow(models.ConsentSMS, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec("set_config").
+					WithArgs("acme").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("GROUP BY p.consent_id").
+					WillReturnRows(mock.NewRows(countColumns))
+				mock.ExpectCommit()
+
+				res, e = retention.Purge(
+					context.TODO(),
+					PurgeOptions{DryRun: true})
+			})
+
+			It("does not return error", func() {
+				Expect(e).NotTo(HaveOccurred())
+			})
+
+			It("reports events that would be purged", func() {
+				Expect(res).To(Equal(&models.PurgeReport{
+					DryRun: true,
+					Events: 4,
+					Tenants: []models.TenantPurge{
+						{
+							TenantID: DefaultTenant,
+							Events:   4,
+							Consents: map[string]int64{
+								models.ConsentEmail: 3,
+								models.ConsentSMS:   1,
+							},
+						},
+					},
+				}))
+			})
+
+			It("counts every tenant in its own scope", func() {
+				Expect(mock.ExpectationsWereMet()).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("purge", func() {
+			var (
+				res *models.PurgeReport
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenants(DefaultTenant)
+				expectTenantTx(mock)
+				mock.ExpectQuery("DELETE FROM \"events\"").
+					WithArgs(2).
+					WillReturnRows(mock.NewRows(countColumns).
+						AddRow(models.ConsentEmail, 2))
+				mock.ExpectCommit()
+				expectTenantTx(mock)
+				mock.ExpectQuery("INSERT INTO \"event_tombstones\"").
+					WithArgs(2).
+					WillReturnRows(mock.NewRows(countColumns).
+						AddRow(models.ConsentSMS, 1))
+				mock.ExpectCommit()
+
+				res, e = retention.Purge(
+					context.TODO(),
+					PurgeOptions{BatchSize: 2})
+			})
+
+			It("does not return error", func() {
+				Expect(e).NotTo(HaveOccurred())
+			})
+
+			It("deletes in batches until a batch is not full", func() {
+				Expect(res.Events).To(Equal(int64(3)))
+				Expect(res.Tenants[0].Consents).To(Equal(map[string]int64{
+					models.ConsentEmail: 2,
+					models.ConsentSMS:   1,
+				}))
+				Expect(mock.ExpectationsWereMet()).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("batch error", func() {
+			var (
+				res *models.PurgeReport
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenants(DefaultTenant)
+				expectTenantTx(mock)
+				mock.ExpectQuery("DELETE FROM \"events\"").
+					WithArgs(DefaultPurgeBatchSize).
+					WillReturnError(errors.New("lock timeout"))
+				mock.ExpectRollback()
+
+				res, e = retention.Purge(context.TODO(), PurgeOptions{})
+			})
+
+			It("returns error", func() {
+				Expect(e).To(MatchError("lock timeout"))
+			})
+
+			It("returns partial report", func() {
+				Expect(res.Events).To(BeZero())
+			})
+		})
+
+		Context("cancelled", func() {
+			var e error
+
+			BeforeEach(func() {
+				ctx, cancel := context.WithCancel(context.TODO())
+				cancel()
+
+				expectTenants(DefaultTenant)
+
+				_, e = retention.Purge(ctx, PurgeOptions{})
+			})
+
+			It("returns context error", func() {
+				Expect(e).To(MatchError(context.Canceled))
+			})
+		})
+	})
+})
@@ -19,6 +19,54 @@ func NewSQLMock() (*sql.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+func expectTenantTx(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("set_config").
+		WithArgs(DefaultTenant).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+const mockProfile = "user@example.com\n\n\n"
+
+func expectProfile(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT concat\\(").
+		WillReturnRows(mock.NewRows([]string{"profile"}).AddRow(mockProfile))
+}
+
+func expectOfferedConsents(mock sqlmock.Sqlmock, consentIDs ...string) {
+	rows := mock.NewRows([]string{"consent_id"})
+
+	for _, consentID := range consentIDs {
+		rows.AddRow(consentID)
+	}
+
+	mock.ExpectQuery("FROM \"tenant_consents\"").WillReturnRows(rows)
+}
+
+func expectIntactChains(mock sqlmock.Sqlmock, userIDs ...string) {
+	rows := mock.NewRows([]string{
+		"id",
+		"event_hash",
+		"chain_start",
+		"id",
+		"consent_id",
+		"sequence",
+		"enabled",
+		"phone_number",
+		"occurred_at",
+		"created_at",
+		"previous_hash",
+		"hash",
+		"purged",
+	})
+
+	for _, userID := range userIDs {
+		rows.AddRow(userID, nil, 1, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	}
+
+	mock.ExpectQuery("LEFT JOIN").WillReturnRows(rows)
+}
+
 func Test(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Services Suite")
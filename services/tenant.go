@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+const DefaultTenant = "default"
+
+type Tenant interface {
+	ByAPIKey(ctx context.Context, key string) (string, error)
+
+	Exists(ctx context.Context, id string) error
+
+	List(ctx context.Context) ([]string, error)
+}
+
+type PostgresTenant struct {
+	db *sql.DB
+}
+
+func NewTenant(db *sql.DB) Tenant {
+	return &PostgresTenant{db}
+}
+
+func (t *PostgresTenant) ByAPIKey(
+	ctx context.Context,
+	key string) (string, error) {
+
+	const query = `SELECT tenant_id FROM "api_keys" WHERE hash = $1 AND revoked_at IS NULL`
+
+	ctx, span := tracing.Start(ctx, "PostgresTenant.ByAPIKey")
+	defer span.End()
+
+	var tenantID string
+
+	if err := t.db.QueryRowContext(ctx, query, HashAPIKey(key)).
+		Scan(&tenantID); err != nil {
+		return "", translateError(err)
+	}
+
+	return tenantID, nil
+}
+
+func (t *PostgresTenant) Exists(ctx context.Context, id string) error {
+	const query = `SELECT id FROM "tenants" WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresTenant.Exists")
+	defer span.End()
+
+	return translateError(t.db.QueryRowContext(ctx, query, id).Scan(&id))
+}
+
+func (t *PostgresTenant) List(ctx context.Context) ([]string, error) {
+	const query = `SELECT id FROM "tenants" ORDER BY id`
+
+	ctx, span := tracing.Start(ctx, "PostgresTenant.List")
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	tenants := make([]string, 0)
+
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		tenants = append(tenants, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tenants, nil
+}
+
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+type tenantKey struct{}
+
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+func TenantID(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id
+	}
+
+	return DefaultTenant
+}
+
+func beginTx(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	const query = `SELECT set_config('app.tenant_id', $1, TRUE), set_config('role', 'consents_tenant', TRUE)`
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelDefault})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, TenantID(ctx)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func inTenant(
+	ctx context.Context,
+	db *sql.DB,
+	fn func(tx *sql.Tx) error) error {
+
+	tx, err := beginTx(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tenant", func() {
+	var (
+		db     *sql.DB
+		mock   sqlmock.Sqlmock
+		tenant Tenant
+	)
+
+	BeforeEach(func() {
+		db, mock = NewSQLMock()
+		tenant = NewTenant(db)
+	})
+
+	Describe("ByAPIKey", func() {
+		Context("active key", func() {
+			var (
+				res string
+				e   error
+			)
+
+			BeforeEach(func() {
+				mock.ExpectQuery("FROM \"api_keys\"").
+					WithArgs(HashAPIKey("secret")).
+					WillReturnRows(mock.NewRows([]string{"tenant_id"}).AddRow("acme"))
+
+				res, e = tenant.ByAPIKey(context.TODO(), "secret")
+			})
+
+			It("returns tenant of key", func() {
+				Expect(e).To(BeNil())
+				Expect(res).To(Equal("acme"))
+			})
+		})
+
+		Context("unknown key", func() {
+			var e error
+
+			BeforeEach(func() {
+				mock.ExpectQuery("FROM \"api_keys\"").
+					WithArgs(HashAPIKey("secret")).
+					WillReturnError(sql.ErrNoRows)
+
+				_, e = tenant.ByAPIKey(context.TODO(), "secret")
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Exists", func() {
+		Context("existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				mock.ExpectQuery("FROM \"tenants\"").
+					WithArgs("acme").
+					WillReturnRows(mock.NewRows([]string{"id"}).AddRow("acme"))
+
+				e = tenant.Exists(context.TODO(), "acme")
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				mock.ExpectQuery("FROM \"tenants\"").
+					WithArgs("acme").
+					WillReturnError(sql.ErrNoRows)
+
+				e = tenant.Exists(context.TODO(), "acme")
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("List", func() {
+		var res []string
+
+		BeforeEach(func() {
+			mock.ExpectQuery("FROM \"tenants\" ORDER BY id").
+				WillReturnRows(mock.NewRows([]string{"id"}).AddRow("acme").AddRow("default"))
+
+			res, _ = tenant.List(context.TODO())
+		})
+
+		It("returns every tenant", func() {
+			Expect(res).To(Equal([]string{"acme", "default"}))
+		})
+	})
+
+	Describe("TenantID", func() {
+		It("defaults to default tenant", func() {
+			Expect(TenantID(context.TODO())).To(Equal(DefaultTenant))
+		})
+
+		It("returns tenant of context", func() {
+			Expect(TenantID(WithTenant(context.TODO(), "acme"))).To(Equal("acme"))
+		})
+	})
+
+	Describe("scoped queries", func() {
+		var e error
+
+		BeforeEach(func() {
+			mock.ExpectBegin()
+			mock.ExpectExec("set_config\\('app.tenant_id', \\$1, TRUE\\), set_config\\('role'").
+				WithArgs("acme").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("FROM \"user_merges\"").
+				WillReturnError(sql.ErrNoRows)
+			mock.ExpectRollback()
+
+			_, e = NewUser(db, nil).MergedInto(
+				WithTenant(context.TODO(), "acme"),
+				generateID())
+		})
+
+		It("run as tenant of context", func() {
+			Expect(e).To(MatchError(ErrNotFound))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
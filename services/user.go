@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
+	"time"
 
+	"github.com/kazimanzurrashid/consents-api-go/metrics"
 	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
 )
 
 type User interface {
@@ -13,117 +16,409 @@ type User interface {
 		ctx context.Context,
 		request *models.UserCreateRequest) (*models.User, error)
 
+	Upsert(
+		ctx context.Context,
+		request *models.UserCreateRequest) (*models.User, bool, error)
+
 	Delete(ctx context.Context, id string) error
 
 	Detail(ctx context.Context, id string) (*models.User, error)
+
+	DetailByIdentifier(
+		ctx context.Context,
+		identifier models.Identifier) (*models.User, error)
+
+	AttachIdentifier(
+		ctx context.Context,
+		userID string,
+		identifier models.Identifier) (bool, error)
+
+	DetachIdentifier(
+		ctx context.Context,
+		userID string,
+		identifier models.Identifier) error
+
+	DetailByPhone(ctx context.Context, number string) (*models.User, error)
+
+	AttachPhone(
+		ctx context.Context,
+		userID string,
+		phone models.Phone) (*models.Phone, bool, error)
+
+	DetachPhone(ctx context.Context, userID string, number string) error
+
+	Merge(
+		ctx context.Context,
+		id string,
+		request *models.UserMergeRequest) (*models.User, error)
+
+	MergedInto(ctx context.Context, id string) (string, error)
+
+	Device(ctx context.Context, id string) (*models.Device, error)
+
+	Identify(
+		ctx context.Context,
+		id string,
+		request *models.DeviceIdentifyRequest) (*models.User, error)
+
+	Export(ctx context.Context, id string) (*models.UserExport, error)
+
+	LegalHold(ctx context.Context, id string) (*models.LegalHold, error)
+
+	PlaceLegalHold(ctx context.Context, id string) (*models.LegalHold, error)
+
+	ReleaseLegalHold(ctx context.Context, id string) error
 }
 
 type PostgresUser struct {
-	db *sql.DB
+	db       *sql.DB
+	receipts *ReceiptIssuer
 }
 
-func NewUser(db *sql.DB) User {
-	return &PostgresUser{db}
+func NewUser(db *sql.DB, receipts *ReceiptIssuer) User {
+	return &PostgresUser{db, receipts}
 }
 
 func (u *PostgresUser) Create(
 	ctx context.Context,
 	request *models.UserCreateRequest) (*models.User, error) {
 
-	const query = `INSERT INTO "users"(id, email) VALUES($1, $2)`
+	ctx, span := tracing.Start(ctx, "PostgresUser.Create")
+	defer span.End()
+
+	user, _, err := u.create(ctx, request, false)
+
+	return user, err
+}
+
+func (u *PostgresUser) Upsert(
+	ctx context.Context,
+	request *models.UserCreateRequest) (*models.User, bool, error) {
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Upsert")
+	defer span.End()
+
+	return u.create(ctx, request, true)
+}
+
+func (u *PostgresUser) create(
+	ctx context.Context,
+	request *models.UserCreateRequest,
+	upsert bool) (*models.User, bool, error) {
+
+	const (
+		insertQuery = `INSERT INTO "users"(id, email, external_id, event_sequence) VALUES($1, $2, $3, $4)`
+		upsertQuery = insertQuery + ` ON CONFLICT DO NOTHING`
+		phoneQuery  = `INSERT INTO "user_phones"(number, user_id, is_primary) VALUES($1, $2, TRUE)`
+	)
+
+	query := insertQuery
+
+	if upsert {
+		query = upsertQuery
+	}
+
 	id := generateID()
 
-	if _, err := u.db.ExecContext(ctx, query, id, request.Email); err != nil {
-		return nil, err
+	tx, err := beginTx(ctx, u.db)
+
+	if err != nil {
+		return nil, false, err
 	}
 
-	return &models.User{
-		ID:       id,
-		Email:    request.Email,
-		Consents: make([]models.Consent, 0),
-	}, nil
-}
+	if upsert {
+		user, err := existingUser(ctx, tx, request)
 
-func (u *PostgresUser) Delete(ctx context.Context, id string) error {
-	const eventsQuery = `DELETE FROM "events" WHERE user_id = $1`
-	const userQuery = `DELETE FROM "users" WHERE id = $1`
+		if !errors.Is(err, ErrConflict) {
+			_ = tx.Rollback()
+			return user, false, err
+		}
+	}
 
-	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelDefault})
+	result, err := tx.ExecContext(
+		ctx,
+		query,
+		id,
+		sql.NullString{
+			String: request.Email,
+			Valid:  request.Email != "",
+		},
+		sql.NullString{
+			String: request.ExternalID,
+			Valid:  request.ExternalID != "",
+		},
+		len(request.Consents))
 
 	if err != nil {
-		return err
+		_ = tx.Rollback()
+		return nil, false, translateError(err)
 	}
 
-	if _, err := tx.ExecContext(ctx, eventsQuery, id); err != nil {
+	affected, err := result.RowsAffected()
+
+	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return nil, false, err
 	}
 
-	if _, err := tx.ExecContext(ctx, userQuery, id); err != nil {
+	if affected == 0 {
+		user, err := existingUser(ctx, tx, request)
 		_ = tx.Rollback()
-		return err
+
+		return user, false, err
 	}
 
-	return tx.Commit()
-}
+	if err := requirePhone(request.Consents, request.Phone); err != nil {
+		_ = tx.Rollback()
+		return nil, false, err
+	}
 
-func (u *PostgresUser) Detail(
-	ctx context.Context,
-	id string) (*models.User, error) {
+	phones := make([]models.Phone, 0, 1)
 
-	const userQuery = `SELECT id, email FROM "users" WHERE id = $1`
-	const eventsQuery = `
-(SELECT consent_id, enabled
-FROM "events"
-WHERE user_id = $1
-AND consent_id = $%v
-ORDER BY created_at
-DESC LIMIT 1)`
+	if request.Phone != "" {
+		if _, err := tx.ExecContext(
+			ctx,
+			phoneQuery,
+			request.Phone,
+			id); err != nil {
+			_ = tx.Rollback()
+			return nil, false, translateError(err)
+		}
 
-	userRow := u.db.QueryRowContext(ctx, userQuery, id)
+		phones = append(phones, models.Phone{
+			Number:  request.Phone,
+			Primary: true,
+		})
+	}
 
-	var user models.User
+	events := newEvents(
+		id,
+		request.Consents,
+		1,
+		request.Phone,
+		time.Now().UTC().Truncate(time.Microsecond))
+	heads := map[string]string{id: ""}
+	chainEvents(events, heads)
 
-	if err := userRow.Scan(&user.ID, &user.Email); err != nil {
-		return nil, nil
+	if err := appendEvents(ctx, tx, events, heads); err != nil {
+		_ = tx.Rollback()
+		return nil, false, err
 	}
 
-	var consentsQuery string
-	values := []interface{}{id}
+	var receiptID string
 
-	for index, consentID := range []string{
-		models.ConsentEmail,
-		models.ConsentSMS} {
-		if index > 0 {
-			consentsQuery += " UNION ALL "
+	if u.receipts != nil && len(events) > 0 {
+		if receiptID, err = u.receipts.issue(ctx, tx, events); err != nil {
+			_ = tx.Rollback()
+			return nil, false, err
 		}
-		consentsQuery += fmt.Sprintf(eventsQuery, index+2)
-		values = append(values, consentID)
 	}
 
-	eventRows, err := u.db.QueryContext(ctx, consentsQuery, values...)
+	consents, version, err := latestConsents(ctx, tx, id)
 
 	if err != nil {
-		return nil, err
+		_ = tx.Rollback()
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
 	}
 
-	defer func() {
-		_ = eventRows.Close()
-	}()
+	for _, event := range events {
+		metrics.ConsentChanged(event.ConsentID, event.Enabled)
+	}
 
-	consents := make([]models.Consent, 0)
+	return &models.User{
+		ID:          id,
+		Email:       request.Email,
+		ExternalID:  request.ExternalID,
+		Consents:    consents,
+		Phones:      phones,
+		Identifiers: make([]models.Identifier, 0),
+		ReceiptID:   receiptID,
+		Version:     version,
+	}, true, nil
+}
 
-	for eventRows.Next() {
-		var consent models.Consent
+func existingUser(
+	ctx context.Context,
+	tx *sql.Tx,
+	request *models.UserCreateRequest) (*models.User, error) {
 
-		if err := eventRows.Scan(&consent.ID, &consent.Enabled); err != nil {
-			return nil, err
+	const (
+		byEmailQuery      = `SELECT id, email, external_id FROM "users" WHERE email = $1`
+		byExternalIDQuery = `SELECT id, email, external_id FROM "users" WHERE external_id = $1`
+		byPhoneQuery      = `SELECT u.id, u.email, u.external_id FROM "users" u JOIN "user_phones" p ON p.user_id = u.id WHERE p.number = $1`
+		byMergedQuery     = `SELECT u.id, u.email, u.external_id FROM "user_merges" m JOIN "users" u ON u.id = m.to_id WHERE m.external_id = $1 OR m.email = $2 ORDER BY m.merged_at DESC LIMIT 1`
+	)
+
+	var (
+		row        *sql.Row
+		constraint string
+	)
+
+	switch {
+	case request.ExternalID != "":
+		row = tx.QueryRowContext(ctx, byExternalIDQuery, request.ExternalID)
+		constraint = "uq_external_id"
+
+		if request.Email != "" {
+			constraint = "uq_email"
 		}
+	case request.Email != "":
+		row = tx.QueryRowContext(ctx, byEmailQuery, request.Email)
+		constraint = "uq_email"
+	default:
+		row = tx.QueryRowContext(ctx, byPhoneQuery, request.Phone)
+		constraint = "pk_user_phones"
+	}
+
+	user, err := scanUser(row)
+
+	if errors.Is(err, sql.ErrNoRows) &&
+		(request.ExternalID != "" || request.Email != "") {
+		user, err = scanUser(tx.QueryRowContext(
+			ctx,
+			byMergedQuery,
+			request.ExternalID,
+			request.Email))
+	}
 
-		consents = append(consents, consent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, uniqueViolation(constraint)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := populateUser(ctx, tx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func populateUser(ctx context.Context, q queryer, user *models.User) error {
+	consents, version, err := latestConsents(ctx, q, user.ID)
+
+	if err != nil {
+		return err
+	}
+
+	phones, err := userPhones(ctx, q, user.ID)
+
+	if err != nil {
+		return err
+	}
+
+	identifiers, err := userIdentifiers(ctx, q, user.ID)
+
+	if err != nil {
+		return err
 	}
 
 	user.Consents = consents
+	user.Phones = phones
+	user.Identifiers = identifiers
+	user.Version = version
+
+	return nil
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	var (
+		user       models.User
+		email      sql.NullString
+		externalID sql.NullString
+	)
+
+	if err := row.Scan(&user.ID, &email, &externalID); err != nil {
+		return nil, err
+	}
+
+	user.Email = email.String
+	user.ExternalID = externalID.String
 
 	return &user, nil
 }
+
+func (u *PostgresUser) Delete(ctx context.Context, id string) error {
+	const holdQuery = `SELECT legal_hold_at FROM "users" WHERE id = $1 FOR UPDATE`
+	const eventsQuery = `DELETE FROM "events" WHERE user_id = $1`
+	const userQuery = `DELETE FROM "users" WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Delete")
+	defer span.End()
+
+	tx, err := beginTx(ctx, u.db)
+
+	if err != nil {
+		return err
+	}
+
+	hold, err := scanLegalHold(id, tx.QueryRowContext(ctx, holdQuery, id))
+
+	if err != nil {
+		_ = tx.Rollback()
+		return translateError(err)
+	}
+
+	if hold.Held {
+		_ = tx.Rollback()
+		return ErrLegalHold
+	}
+
+	if _, err := tx.ExecContext(ctx, eventsQuery, id); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, userQuery, id)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		_ = tx.Rollback()
+		return ErrNotFound
+	}
+
+	return tx.Commit()
+}
+
+func (u *PostgresUser) Detail(
+	ctx context.Context,
+	id string) (*models.User, error) {
+
+	const userQuery = `SELECT id, email, external_id FROM "users" WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Detail")
+	defer span.End()
+
+	var user *models.User
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		var err error
+
+		if user, err = scanUser(tx.QueryRowContext(ctx, userQuery, id)); err != nil {
+			return translateError(err)
+		}
+
+		return populateUser(ctx, tx, user)
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (u *PostgresUser) Device(
+	ctx context.Context,
+	id string) (*models.Device, error) {
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Device")
+	defer span.End()
+
+	device := &models.Device{ID: id}
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		userID, anonymous, err := deviceOwner(ctx, tx.QueryRowContext, id, false)
+
+		if err != nil {
+			return err
+		}
+
+		if device.Consents, device.Version, err = latestConsents(
+			ctx,
+			tx,
+			userID); err != nil {
+			return err
+		}
+
+		if !anonymous {
+			device.UserID = userID
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (u *PostgresUser) Identify(
+	ctx context.Context,
+	id string,
+	request *models.DeviceIdentifyRequest) (*models.User, error) {
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Identify")
+	defer span.End()
+
+	tx, err := beginTx(ctx, u.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	subjectID, anonymous, err := deviceOwner(ctx, tx.QueryRowContext, id, true)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	var user *models.User
+
+	if anonymous && subjectID != request.UserID {
+		user, err = mergeUsers(ctx, tx, request.UserID, subjectID, id)
+
+		if errors.Is(err, ErrNotFound) {
+			err = ErrUserNotFound
+		}
+	} else {
+		user, err = linkDevice(ctx, tx, id, subjectID, request.UserID)
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func linkDevice(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string,
+	ownerID string,
+	userID string) (*models.User, error) {
+
+	const (
+		linkQuery = `UPDATE "devices" SET user_id = $2 WHERE id = $1`
+		userQuery = `SELECT id, email, external_id FROM "users" WHERE id = $1`
+	)
+
+	if ownerID != userID {
+		if _, err := tx.ExecContext(ctx, linkQuery, id, userID); err != nil {
+			return nil, translateError(err)
+		}
+	}
+
+	user, err := scanUser(tx.QueryRowContext(ctx, userQuery, userID))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := populateUser(ctx, tx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func deviceOwner(
+	ctx context.Context,
+	queryRow func(context.Context, string, ...interface{}) *sql.Row,
+	id string,
+	lock bool) (string, bool, error) {
+
+	const query = `
+SELECT d.user_id, u.anonymous
+FROM "devices" d
+JOIN "users" u ON u.id = d.user_id
+WHERE d.id = $1`
+
+	statement := query
+
+	if lock {
+		statement += ` FOR UPDATE OF d`
+	}
+
+	var (
+		userID    string
+		anonymous bool
+	)
+
+	if err := queryRow(ctx, statement, id).
+		Scan(&userID, &anonymous); err != nil {
+		return "", false, translateError(err)
+	}
+
+	return userID, anonymous, nil
+}
+
+func deviceSubject(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string) (string, error) {
+
+	const (
+		selectQuery  = `SELECT user_id FROM "devices" WHERE id = $1`
+		userQuery    = `INSERT INTO "users"(id, anonymous) VALUES($1, TRUE)`
+		deviceQuery  = `INSERT INTO "devices"(id, user_id) VALUES($1, $2) ON CONFLICT DO NOTHING`
+		discardQuery = `DELETE FROM "users" WHERE id = $1`
+	)
+
+	var userID string
+
+	err := tx.QueryRowContext(ctx, selectQuery, id).Scan(&userID)
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return userID, err
+	}
+
+	userID = generateID()
+
+	if _, err := tx.ExecContext(ctx, userQuery, userID); err != nil {
+		return "", err
+	}
+
+	result, err := tx.ExecContext(ctx, deviceQuery, id, userID)
+
+	if err != nil {
+		return "", err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return "", err
+	}
+
+	if affected > 0 {
+		return userID, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, discardQuery, userID); err != nil {
+		return "", err
+	}
+
+	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(&userID)
+
+	return userID, err
+}
+
+func subjectOf(
+	ctx context.Context,
+	tx *sql.Tx,
+	request *models.EventCreateRequest) (string, error) {
+
+	if request.Device == nil {
+		return request.User.ID, nil
+	}
+
+	return deviceSubject(ctx, tx, request.Device.ID)
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("User", func() {
+	const deviceID = "cookie-4f1c"
+
+	var (
+		id        string
+		subjectID string
+
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		user User
+	)
+
+	BeforeEach(func() {
+		id = generateID()
+		subjectID = generateID()
+
+		db, mock = NewSQLMock()
+		user = NewUser(db, nil)
+	})
+
+	Describe("Device", func() {
+		Context("anonymous", func() {
+			var res *models.Device
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"devices\"").
+					WithArgs(deviceID).
+					WillReturnRows(mock.NewRows([]string{"user_id", "anonymous"}).
+						AddRow(subjectID, true))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(subjectID).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, false, nil, time.Now()))
+				mock.ExpectCommit()
+
+				res, _ = user.Device(context.TODO(), deviceID)
+			})
+
+			It("returns consents without user", func() {
+				Expect(res.ID).To(Equal(deviceID))
+				Expect(res.UserID).To(BeEmpty())
+				Expect(res.Consents).To(HaveLen(1))
+				Expect(res.Version).NotTo(BeEmpty())
+			})
+		})
+
+		Context("identified", func() {
+			var res *models.Device
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"devices\"").
+					WithArgs(deviceID).
+					WillReturnRows(mock.NewRows([]string{"user_id", "anonymous"}).
+						AddRow(id, false))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectCommit()
+
+				res, _ = user.Device(context.TODO(), deviceID)
+			})
+
+			It("returns user of device", func() {
+				Expect(res.UserID).To(Equal(id))
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"devices\"").
+					WithArgs(deviceID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.Device(context.TODO(), deviceID)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Identify", func() {
+		Context("anonymous device", func() {
+			var (
+				res *models.User
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE OF d").
+					WithArgs(deviceID).
+					WillReturnRows(mock.NewRows([]string{"user_id", "anonymous"}).
+						AddRow(subjectID, true))
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "user@example.com", nil).
+						AddRow(subjectID, nil, nil))
+				expectIntactChains(mock, id, subjectID)
+				mock.ExpectQuery("SELECT count").
+					WithArgs(subjectID).
+					WillReturnRows(mock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(id, 1).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).
+						AddRow(4, nil, nil))
+				mock.ExpectExec("UPDATE \"events\"").
+					WithArgs(id, subjectID, int64(4)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"user_identifiers\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"user_phones\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"devices\"").
+					WithArgs(id, subjectID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"receipts\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"email_changes\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"user_merges\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO \"user_merges\"").
+					WithArgs(subjectID, id, nil, nil, deviceID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("SET legal_hold_at").
+					WithArgs(id, subjectID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM \"users\"").
+					WithArgs(subjectID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("WHERE user_id = \\$1 AND sequence >= \\$2").
+					WithArgs(id, int64(4)).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"consent_id",
+						"sequence",
+						"enabled",
+						"phone_number",
+						"occurred_at",
+						"created_at",
+						"hash",
+						"purged"}).
+						AddRow(generateID(), models.ConsentEmail, 4, true, nil, time.Now(), time.Now(), nil, false))
+				mock.ExpectExec("UPDATE \"events\" e\\s+SET previous_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("COALESCE").
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "user@example.com", nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, false, nil, time.Now()))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectCommit()
+
+				res, e = user.Identify(
+					context.TODO(),
+					deviceID,
+					&models.DeviceIdentifyRequest{UserID: id})
+			})
+
+			It("folds device consents into user", func() {
+				Expect(e).To(BeNil())
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Consents).To(HaveLen(1))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("device of another user", func() {
+			var (
+				res *models.User
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE OF d").
+					WithArgs(deviceID).
+					WillReturnRows(mock.NewRows([]string{"user_id", "anonymous"}).
+						AddRow(subjectID, false))
+				mock.ExpectExec("UPDATE \"devices\"").
+					WithArgs(deviceID, id).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "user@example.com", nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectCommit()
+
+				res, e = user.Identify(
+					context.TODO(),
+					deviceID,
+					&models.DeviceIdentifyRequest{UserID: id})
+			})
+
+			It("links device without moving history", func() {
+				Expect(e).To(BeNil())
+				Expect(res.ID).To(Equal(id))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("non-existent device", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE OF d").
+					WithArgs(deviceID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.Identify(
+					context.TODO(),
+					deviceID,
+					&models.DeviceIdentifyRequest{UserID: id})
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+
+		Context("non-existent user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE OF d").
+					WithArgs(deviceID).
+					WillReturnRows(mock.NewRows([]string{"user_id", "anonymous"}).
+						AddRow(subjectID, true))
+				mock.ExpectQuery("FOR UPDATE").
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(subjectID, nil, nil))
+				mock.ExpectRollback()
+
+				_, e = user.Identify(
+					context.TODO(),
+					deviceID,
+					&models.DeviceIdentifyRequest{UserID: id})
+			})
+
+			It("returns user not found error", func() {
+				Expect(e).To(MatchError(ErrUserNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (u *PostgresUser) Export(
+	ctx context.Context,
+	id string) (*models.UserExport, error) {
+
+	const (
+		userQuery = `SELECT id, email, external_id FROM "users" WHERE id = $1`
+		holdQuery = `SELECT legal_hold_at FROM "users" WHERE id = $1`
+	)
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Export")
+	defer span.End()
+
+	res := &models.UserExport{
+		GeneratedAt: time.Now().UTC(),
+		TenantID:    TenantID(ctx),
+	}
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		user, err := scanUser(tx.QueryRowContext(ctx, userQuery, id))
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		if err := populateUser(ctx, tx, user); err != nil {
+			return err
+		}
+
+		res.User = *user
+
+		hold, err := scanLegalHold(id, tx.QueryRowContext(ctx, holdQuery, id))
+
+		if err != nil {
+			return err
+		}
+
+		res.LegalHold = *hold
+
+		if res.Devices, err = userDevices(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if res.Receipts, err = userReceipts(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if res.Events, err = userEvents(ctx, tx, id, res.Receipts); err != nil {
+			return err
+		}
+
+		if res.Tombstones, err = userTombstones(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if res.EmailChanges, err = userEmailChanges(ctx, tx, id); err != nil {
+			return err
+		}
+
+		res.Merges, err = userMerges(ctx, tx, id)
+
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func userDevices(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.ExportDevice, error) {
+
+	const query = `SELECT id, created_at FROM "devices" WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	devices := make([]models.ExportDevice, 0)
+
+	for rows.Next() {
+		var device models.ExportDevice
+
+		if err := rows.Scan(&device.ID, &device.LinkedAt); err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func userEvents(
+	ctx context.Context,
+	q queryer,
+	userID string,
+	receipts []models.Receipt) ([]models.ExportEvent, error) {
+
+	const query = `
+SELECT e.id, e.consent_id, e.sequence, e.enabled, e.phone_number, e.occurred_at, e.created_at, e.previous_hash, e.hash, e.merged_from, m.device_id
+FROM "events" e
+LEFT JOIN "user_merges" m ON m.from_id = e.merged_from
+WHERE e.user_id = $1
+ORDER BY e.sequence`
+
+	receiptIDs := make(map[string]string)
+
+	for _, receipt := range receipts {
+		for _, service := range receipt.Receipt.Services {
+			for _, purpose := range service.Purposes {
+				receiptIDs[purpose.EventID] = receipt.ID
+			}
+		}
+	}
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	events := make([]models.ExportEvent, 0)
+
+	for rows.Next() {
+		var (
+			event        = models.ExportEvent{Event: models.Event{UserID: userID}}
+			phoneNumber  sql.NullString
+			previousHash sql.NullString
+			hash         sql.NullString
+			mergedFrom   sql.NullString
+			deviceID     sql.NullString
+		)
+
+		if err := rows.Scan(
+			&event.ID,
+			&event.ConsentID,
+			&event.Sequence,
+			&event.Enabled,
+			&phoneNumber,
+			&event.OccurredAt,
+			&event.CreatedAt,
+			&previousHash,
+			&hash,
+			&mergedFrom,
+			&deviceID); err != nil {
+			return nil, err
+		}
+
+		event.PhoneNumber = phoneNumber.String
+		event.PreviousHash = previousHash.String
+		event.Hash = hash.String
+		event.ReceiptID = receiptIDs[event.ID]
+		event.MergedFrom = eventOrigin(mergedFrom, deviceID)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func userTombstones(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.ExportTombstone, error) {
+
+	const query = `
+SELECT t.event_id, t.sequence, t.previous_hash, t.hash, t.purged_at, t.merged_from, m.device_id
+FROM "event_tombstones" t
+LEFT JOIN "user_merges" m ON m.from_id = t.merged_from
+WHERE t.user_id = $1
+ORDER BY t.sequence`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	tombstones := make([]models.ExportTombstone, 0)
+
+	for rows.Next() {
+		var (
+			tombstone    models.ExportTombstone
+			previousHash sql.NullString
+			hash         sql.NullString
+			mergedFrom   sql.NullString
+			deviceID     sql.NullString
+		)
+
+		if err := rows.Scan(
+			&tombstone.EventID,
+			&tombstone.Sequence,
+			&previousHash,
+			&hash,
+			&tombstone.PurgedAt,
+			&mergedFrom,
+			&deviceID); err != nil {
+			return nil, err
+		}
+
+		tombstone.PreviousHash = previousHash.String
+		tombstone.Hash = hash.String
+		tombstone.MergedFrom = eventOrigin(mergedFrom, deviceID)
+		tombstones = append(tombstones, tombstone)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tombstones, nil
+}
+
+func eventOrigin(mergedFrom, deviceID sql.NullString) *models.EventOrigin {
+	if !mergedFrom.Valid {
+		return nil
+	}
+
+	return &models.EventOrigin{
+		UserID:   mergedFrom.String,
+		DeviceID: deviceID.String,
+	}
+}
+
+func userEmailChanges(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.EmailChange, error) {
+
+	const query = `SELECT previous_email, email, changed_at FROM "email_changes" WHERE user_id = $1 ORDER BY changed_at NULLS FIRST`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	changes := make([]models.EmailChange, 0)
+
+	for rows.Next() {
+		var (
+			change        models.EmailChange
+			previousEmail sql.NullString
+			changedAt     sql.NullTime
+		)
+
+		if err := rows.Scan(&previousEmail, &change.Email, &changedAt); err != nil {
+			return nil, err
+		}
+
+		change.PreviousEmail = previousEmail.String
+
+		if changedAt.Valid {
+			change.ChangedAt = &changedAt.Time
+		}
+
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func userMerges(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.UserMerge, error) {
+
+	const query = `SELECT from_id, email, external_id, device_id, merged_at FROM "user_merges" WHERE to_id = $1 ORDER BY merged_at`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	merges := make([]models.UserMerge, 0)
+
+	for rows.Next() {
+		var (
+			merge      models.UserMerge
+			email      sql.NullString
+			externalID sql.NullString
+			deviceID   sql.NullString
+		)
+
+		if err := rows.Scan(
+			&merge.FromID,
+			&email,
+			&externalID,
+			&deviceID,
+			&merge.MergedAt); err != nil {
+			return nil, err
+		}
+
+		merge.Email = email.String
+		merge.ExternalID = externalID.String
+		merge.DeviceID = deviceID.String
+		merges = append(merges, merge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return merges, nil
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("User", func() {
+	var (
+		id string
+
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		user User
+	)
+
+	BeforeEach(func() {
+		id = generateID()
+
+		db, mock = NewSQLMock()
+		user = NewUser(db, nil)
+	})
+
+	Describe("Export", func() {
+		Context("existent", func() {
+			var (
+				res       *models.UserExport
+				e         error
+				eventID   string
+				receiptID string
+				fromID    string
+				purgedID  string
+				now       time.Time
+			)
+
+			BeforeEach(func() {
+				eventID = generateID()
+				receiptID = generateID()
+				fromID = generateID()
+				purgedID = generateID()
+				now = time.Now().UTC()
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "new@example.com", nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(eventID, models.ConsentEmail, true, nil, time.Now()))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).AddRow(now))
+				mock.ExpectQuery("FROM \"devices\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "created_at"}).
+						AddRow("device-1", now))
+
+				content, _ := json.Marshal(models.ConsentReceipt{
+					ConsentReceiptID: receiptID,
+					Services: []models.ReceiptService{
+						{
+							Service: "consents",
+							Purposes: []models.ReceiptPurpose{
+								{Purpose: models.ConsentEmail, Enabled: true, EventID: eventID},
+							},
+						},
+					},
+				})
+
+				mock.ExpectQuery("FROM \"receipts\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"user_id",
+						"key_id",
+						"receipt",
+						"jws",
+						"created_at",
+					}).AddRow(receiptID, id, "kid", content, "a.b.c", now))
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"consent_id",
+						"sequence",
+						"enabled",
+						"phone_number",
+						"occurred_at",
+						"created_at",
+						"previous_hash",
+						"hash",
+						"merged_from",
+						"device_id",
+					}).AddRow(eventID, models.ConsentEmail, 2, true, nil, now, now, "purged-hash", "hash", fromID, "device-1"))
+				mock.ExpectQuery("FROM \"event_tombstones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{
+						"event_id",
+						"sequence",
+						"previous_hash",
+						"hash",
+						"purged_at",
+						"merged_from",
+						"device_id",
+					}).AddRow(purgedID, 1, nil, "purged-hash", now, nil, nil))
+				mock.ExpectQuery("FROM \"email_changes\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"previous_email", "email", "changed_at"}).
+						AddRow(nil, "old@example.com", nil).
+						AddRow("old@example.com", "new@example.com", now))
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"from_id", "email", "external_id", "device_id", "merged_at"}).
+						AddRow(fromID, nil, "crm-1", "device-1", now))
+				mock.ExpectCommit()
+
+				res, e = user.Export(context.TODO(), id)
+			})
+
+			It("does not return error", func() {
+				Expect(e).NotTo(HaveOccurred())
+			})
+
+			It("returns user with current consents", func() {
+				Expect(res.TenantID).To(Equal(DefaultTenant))
+				Expect(res.User.ID).To(Equal(id))
+				Expect(res.User.Consents).NotTo(BeEmpty())
+			})
+
+			It("returns legal hold", func() {
+				Expect(res.LegalHold).To(Equal(models.LegalHold{
+					UserID:   id,
+					Held:     true,
+					PlacedAt: &now,
+				}))
+			})
+
+			It("returns linked devices", func() {
+				Expect(res.Devices).To(Equal([]models.ExportDevice{
+					{ID: "device-1", LinkedAt: now},
+				}))
+			})
+
+			It("returns events with their receipts", func() {
+				Expect(res.Events).To(HaveLen(1))
+				Expect(res.Events[0].ID).To(Equal(eventID))
+				Expect(res.Events[0].Hash).To(Equal("hash"))
+				Expect(res.Events[0].ReceiptID).To(Equal(receiptID))
+			})
+
+			It("returns where merged events came from", func() {
+				Expect(res.Events[0].MergedFrom).To(Equal(&models.EventOrigin{
+					UserID:   fromID,
+					DeviceID: "device-1",
+				}))
+			})
+
+			It("returns tombstones of purged events", func() {
+				Expect(res.Tombstones).To(Equal([]models.ExportTombstone{
+					{EventID: purgedID, Sequence: 1, Hash: "purged-hash", PurgedAt: now},
+				}))
+			})
+
+			It("returns issued receipts", func() {
+				Expect(res.Receipts).To(HaveLen(1))
+				Expect(res.Receipts[0].Receipt.ConsentReceiptID).To(Equal(receiptID))
+			})
+
+			It("returns email history", func() {
+				Expect(res.EmailChanges).To(Equal([]models.EmailChange{
+					{Email: "old@example.com"},
+					{PreviousEmail: "old@example.com", Email: "new@example.com", ChangedAt: &now},
+				}))
+			})
+
+			It("returns merged users", func() {
+				Expect(res.Merges).To(Equal([]models.UserMerge{
+					{FromID: fromID, ExternalID: "crm-1", DeviceID: "device-1", MergedAt: now},
+				}))
+			})
+
+			It("meets expectations", func() {
+				Expect(mock.ExpectationsWereMet()).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("non-existent", func() {
+			var (
+				res *models.UserExport
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				res, e = user.Export(context.TODO(), id)
+			})
+
+			It("returns nil", func() {
+				Expect(res).To(BeNil())
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+
+		Context("error querying devices", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, nil, nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).AddRow(nil))
+				mock.ExpectQuery("FROM \"devices\"").
+					WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+
+				_, e = user.Export(context.TODO(), id)
+			})
+
+			It("returns error", func() {
+				Expect(e).To(MatchError("connection reset"))
+			})
+		})
+	})
+})
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (u *PostgresUser) DetailByIdentifier(
+	ctx context.Context,
+	identifier models.Identifier) (*models.User, error) {
+
+	const query = `
+SELECT u.id, u.email, u.external_id
+FROM "users" u
+JOIN "user_identifiers" i ON i.user_id = u.id
+WHERE i.namespace = $1
+AND i.external_id = $2`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.DetailByIdentifier")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("identifier.namespace", identifier.Namespace))
+
+	var user *models.User
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		var err error
+
+		if user, err = scanUser(tx.QueryRowContext(
+			ctx,
+			query,
+			identifier.Namespace,
+			identifier.ID)); err != nil {
+			return translateError(err)
+		}
+
+		return populateUser(ctx, tx, user)
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u *PostgresUser) AttachIdentifier(
+	ctx context.Context,
+	userID string,
+	identifier models.Identifier) (bool, error) {
+
+	const (
+		insertQuery = `INSERT INTO "user_identifiers"(namespace, external_id, user_id) VALUES($1, $2, $3) ON CONFLICT (tenant_id, namespace, external_id) DO NOTHING`
+		ownerQuery  = `SELECT user_id FROM "user_identifiers" WHERE namespace = $1 AND external_id = $2`
+	)
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.AttachIdentifier")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("identifier.namespace", identifier.Namespace))
+
+	created := false
+
+	err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			insertQuery,
+			identifier.Namespace,
+			identifier.ID,
+			userID)
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		affected, err := result.RowsAffected()
+
+		if err != nil {
+			return err
+		}
+
+		if affected > 0 {
+			created = true
+			return nil
+		}
+
+		var ownerID string
+
+		if err := tx.QueryRowContext(
+			ctx,
+			ownerQuery,
+			identifier.Namespace,
+			identifier.ID).Scan(&ownerID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrConflict
+			}
+
+			return err
+		}
+
+		if ownerID != userID {
+			return ErrConflict
+		}
+
+		return nil
+	})
+
+	return created, err
+}
+
+func (u *PostgresUser) DetachIdentifier(
+	ctx context.Context,
+	userID string,
+	identifier models.Identifier) error {
+
+	const query = `DELETE FROM "user_identifiers" WHERE user_id = $1 AND namespace = $2 AND external_id = $3`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.DetachIdentifier")
+	defer span.End()
+
+	return inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			userID,
+			identifier.Namespace,
+			identifier.ID)
+
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func userIdentifiers(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.Identifier, error) {
+
+	const query = `SELECT namespace, external_id FROM "user_identifiers" WHERE user_id = $1 ORDER BY namespace, external_id`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	identifiers := make([]models.Identifier, 0)
+
+	for rows.Next() {
+		var identifier models.Identifier
+
+		if err := rows.Scan(&identifier.Namespace, &identifier.ID); err != nil {
+			return nil, err
+		}
+
+		identifiers = append(identifiers, identifier)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return identifiers, nil
+}
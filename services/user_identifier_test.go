@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("User", func() {
+	var (
+		id         string
+		identifier models.Identifier
+
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		user User
+	)
+
+	BeforeEach(func() {
+		id = generateID()
+		identifier = models.Identifier{Namespace: "crm", ID: "42"}
+
+		db, mock = NewSQLMock()
+		user = NewUser(db, nil)
+	})
+
+	Describe("DetailByIdentifier", func() {
+		Context("existent", func() {
+			var res *models.User
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("JOIN \"user_identifiers\"").
+					WithArgs("crm", "42").
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "user@example.com", nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}).
+						AddRow("crm", "42"))
+				mock.ExpectCommit()
+
+				res, _ = user.DetailByIdentifier(context.TODO(), identifier)
+			})
+
+			It("returns matching user", func() {
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Identifiers).To(ConsistOf(identifier))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("JOIN \"user_identifiers\"").
+					WithArgs("crm", "42").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.DetailByIdentifier(context.TODO(), identifier)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("version", func() {
+		var versions []string
+
+		expectDetail := func(profile string, identifiers *sqlmock.Rows) {
+			expectTenantTx(mock)
+			mock.ExpectQuery("FROM \"users\"").
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, "user@example.com", nil))
+			mock.ExpectQuery("SELECT concat\\(").
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"profile"}).AddRow(profile))
+			mock.ExpectQuery("FROM \"events\"").
+				WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+					AddRow("0d5f6c8e-3b0a-4c55-9f0e-8a7d3f1b2c4d", models.ConsentEmail, true, nil, time.Now()))
+			mock.ExpectQuery("FROM \"user_phones\"").
+				WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+			mock.ExpectQuery("FROM \"user_identifiers\"").
+				WillReturnRows(identifiers)
+			mock.ExpectCommit()
+		}
+
+		detail := func() {
+			res, err := user.Detail(context.TODO(), id)
+
+			if err != nil {
+				panic(err)
+			}
+
+			versions = append(versions, res.Version)
+		}
+
+		BeforeEach(func() {
+			versions = nil
+
+			expectDetail(
+				"user@example.com\n\n\n",
+				mock.NewRows([]string{"namespace", "external_id"}))
+			detail()
+
+			expectTenantTx(mock)
+			mock.ExpectExec("INSERT INTO \"user_identifiers\"").
+				WithArgs("crm", "42", id).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if _, err := user.AttachIdentifier(context.TODO(), id, identifier); err != nil {
+				panic(err)
+			}
+
+			expectDetail(
+				"user@example.com\n\ncrm:42\n",
+				mock.NewRows([]string{"namespace", "external_id"}).AddRow("crm", "42"))
+			detail()
+
+			expectTenantTx(mock)
+			mock.ExpectExec("DELETE FROM \"user_identifiers\"").
+				WithArgs(id, "crm", "42").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := user.DetachIdentifier(context.TODO(), id, identifier); err != nil {
+				panic(err)
+			}
+
+			expectDetail(
+				"user@example.com\n\n\n",
+				mock.NewRows([]string{"namespace", "external_id"}))
+			detail()
+		})
+
+		It("changes when an identifier is attached", func() {
+			Expect(versions[1]).NotTo(Equal(versions[0]))
+		})
+
+		It("changes back when the identifier is detached", func() {
+			Expect(versions[2]).NotTo(Equal(versions[1]))
+			Expect(versions[2]).To(Equal(versions[0]))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("AttachIdentifier", func() {
+		Context("new identifier", func() {
+			var (
+				created bool
+				e       error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"user_identifiers\"").
+					WithArgs("crm", "42", id).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				created, e = user.AttachIdentifier(context.TODO(), id, identifier)
+			})
+
+			It("attaches identifier", func() {
+				Expect(e).To(BeNil())
+				Expect(created).To(BeTrue())
+			})
+		})
+
+		Context("already attached to same user", func() {
+			var (
+				created bool
+				e       error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"user_identifiers\"").
+					WithArgs("crm", "42", id).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT user_id FROM \"user_identifiers\"").
+					WithArgs("crm", "42").
+					WillReturnRows(mock.NewRows([]string{"user_id"}).AddRow(id))
+				mock.ExpectCommit()
+
+				created, e = user.AttachIdentifier(context.TODO(), id, identifier)
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+				Expect(created).To(BeFalse())
+			})
+		})
+
+		Context("attached to another user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"user_identifiers\"").
+					WithArgs("crm", "42", id).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT user_id FROM \"user_identifiers\"").
+					WithArgs("crm", "42").
+					WillReturnRows(mock.NewRows([]string{"user_id"}).AddRow(generateID()))
+				mock.ExpectRollback()
+
+				_, e = user.AttachIdentifier(context.TODO(), id, identifier)
+			})
+
+			It("returns conflict error", func() {
+				Expect(e).To(MatchError(ErrConflict))
+			})
+		})
+
+		Context("non-existent user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"user_identifiers\"").
+					WithArgs("crm", "42", id).
+					WillReturnError(&pq.Error{
+						Code:       pqForeignKeyViolation,
+						Constraint: "user_identifiers_users",
+					})
+				mock.ExpectRollback()
+
+				_, e = user.AttachIdentifier(context.TODO(), id, identifier)
+			})
+
+			It("returns user not found error", func() {
+				Expect(e).To(MatchError(ErrUserNotFound))
+			})
+		})
+	})
+
+	Describe("DetachIdentifier", func() {
+		Context("attached", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("DELETE FROM \"user_identifiers\"").
+					WithArgs(id, "crm", "42").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				e = user.DetachIdentifier(context.TODO(), id, identifier)
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+			})
+		})
+
+		Context("not attached", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("DELETE FROM \"user_identifiers\"").
+					WithArgs(id, "crm", "42").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+
+				e = user.DetachIdentifier(context.TODO(), id, identifier)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (u *PostgresUser) LegalHold(
+	ctx context.Context,
+	id string) (*models.LegalHold, error) {
+
+	const query = `SELECT legal_hold_at FROM "users" WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.LegalHold")
+	defer span.End()
+
+	var hold *models.LegalHold
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		var err error
+
+		hold, err = scanLegalHold(id, tx.QueryRowContext(ctx, query, id))
+
+		return err
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return hold, nil
+}
+
+func (u *PostgresUser) PlaceLegalHold(
+	ctx context.Context,
+	id string) (*models.LegalHold, error) {
+
+	const query = `UPDATE "users" SET legal_hold_at = COALESCE(legal_hold_at, now()) WHERE id = $1 RETURNING legal_hold_at`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.PlaceLegalHold")
+	defer span.End()
+
+	var hold *models.LegalHold
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		var err error
+
+		hold, err = scanLegalHold(id, tx.QueryRowContext(ctx, query, id))
+
+		return err
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return hold, nil
+}
+
+func (u *PostgresUser) ReleaseLegalHold(ctx context.Context, id string) error {
+	const query = `UPDATE "users" SET legal_hold_at = NULL WHERE id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.ReleaseLegalHold")
+	defer span.End()
+
+	return inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, id)
+
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func scanLegalHold(userID string, row *sql.Row) (*models.LegalHold, error) {
+	var placedAt sql.NullTime
+
+	if err := row.Scan(&placedAt); err != nil {
+		return nil, err
+	}
+
+	hold := &models.LegalHold{UserID: userID, Held: placedAt.Valid}
+
+	if placedAt.Valid {
+		hold.PlacedAt = &placedAt.Time
+	}
+
+	return hold, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("User", func() {
+	var (
+		id string
+
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		user User
+	)
+
+	BeforeEach(func() {
+		id = generateID()
+
+		db, mock = NewSQLMock()
+		user = NewUser(db, nil)
+	})
+
+	Describe("LegalHold", func() {
+		Context("not held", func() {
+			var res *models.LegalHold
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at FROM \"users\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).
+						AddRow(nil))
+				mock.ExpectCommit()
+
+				res, _ = user.LegalHold(context.TODO(), id)
+			})
+
+			It("returns released hold", func() {
+				Expect(res).To(Equal(&models.LegalHold{UserID: id}))
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at FROM \"users\"").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.LegalHold(context.TODO(), id)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("PlaceLegalHold", func() {
+		Context("existent", func() {
+			var (
+				res *models.LegalHold
+				now time.Time
+			)
+
+			BeforeEach(func() {
+				now = time.Now().UTC()
+
+				expectTenantTx(mock)
+				mock.ExpectQuery("SET legal_hold_at = COALESCE").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).
+						AddRow(now))
+				mock.ExpectCommit()
+
+				res, _ = user.PlaceLegalHold(context.TODO(), id)
+			})
+
+			It("returns placed hold", func() {
+				Expect(res).To(Equal(&models.LegalHold{
+					UserID:   id,
+					Held:     true,
+					PlacedAt: &now,
+				}))
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("SET legal_hold_at = COALESCE").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.PlaceLegalHold(context.TODO(), id)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("ReleaseLegalHold", func() {
+		Context("existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("SET legal_hold_at = NULL").
+					WithArgs(id).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				e = user.ReleaseLegalHold(context.TODO(), id)
+			})
+
+			It("does not return error", func() {
+				Expect(e).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("SET legal_hold_at = NULL").
+					WithArgs(id).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+
+				e = user.ReleaseLegalHold(context.TODO(), id)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+})
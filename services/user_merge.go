@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (u *PostgresUser) Merge(
+	ctx context.Context,
+	id string,
+	request *models.UserMergeRequest) (*models.User, error) {
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.Merge")
+	defer span.End()
+
+	if id == request.UserID {
+		return nil, ErrSameUser
+	}
+
+	tx, err := beginTx(ctx, u.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := mergeUsers(ctx, tx, id, request.UserID, "")
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func mergeUsers(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string,
+	secondaryID string,
+	deviceID string) (*models.User, error) {
+
+	const (
+		countQuery  = `SELECT (SELECT count(*) FROM "events" WHERE user_id = $1) + (SELECT count(*) FROM "event_tombstones" WHERE user_id = $1)`
+		eventsQuery = `
+WITH s AS (
+	SELECT id, purged, row_number() OVER (ORDER BY sequence) AS position
+	FROM (
+		SELECT id, sequence, FALSE AS purged
+		FROM "events"
+		WHERE user_id = $2
+		UNION ALL
+		SELECT event_id, sequence, TRUE
+		FROM "event_tombstones"
+		WHERE user_id = $2) c),
+tombstones AS (
+	UPDATE "event_tombstones" t
+	SET user_id = $1, sequence = $3 + s.position - 1, merged_from = COALESCE(t.merged_from, $2)
+	FROM s
+	WHERE s.purged AND t.event_id = s.id)
+UPDATE "events" e
+SET user_id = $1, sequence = $3 + s.position - 1, merged_from = COALESCE(e.merged_from, $2)
+FROM s
+WHERE NOT s.purged AND e.id = s.id`
+		identifiersQuery = `UPDATE "user_identifiers" SET user_id = $1 WHERE user_id = $2`
+		phonesQuery      = `
+UPDATE "user_phones"
+SET user_id = $1, is_primary = is_primary AND NOT EXISTS(
+	SELECT 1 FROM "user_phones" WHERE user_id = $1 AND is_primary)
+WHERE user_id = $2`
+		devicesQuery   = `UPDATE "devices" SET user_id = $1 WHERE user_id = $2`
+		receiptsQuery  = `UPDATE "receipts" SET user_id = $1 WHERE user_id = $2`
+		emailsQuery    = `UPDATE "email_changes" SET user_id = $1 WHERE user_id = $2`
+		redirectsQuery = `UPDATE "user_merges" SET to_id = $1 WHERE to_id = $2`
+		holdQuery      = `UPDATE "users" SET legal_hold_at = COALESCE(legal_hold_at, (SELECT legal_hold_at FROM "users" WHERE id = $2)) WHERE id = $1`
+		mergeQuery     = `INSERT INTO "user_merges"(from_id, to_id, email, external_id, event_hash, device_id) SELECT $1, $2, $3, $4, event_hash, $5 FROM "users" WHERE id = $1`
+		deleteQuery    = `DELETE FROM "users" WHERE id = $1`
+		userQuery      = `UPDATE "users" SET email = COALESCE(email, $2), external_id = COALESCE(external_id, $3) WHERE id = $1 RETURNING id, email, external_id`
+	)
+
+	users, err := lockUsers(ctx, tx, id, secondaryID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := users[id]; !ok {
+		return nil, ErrNotFound
+	}
+
+	secondary, ok := users[secondaryID]
+
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	if err := verifyChainsOf(ctx, tx, id, secondary.ID); err != nil {
+		return nil, err
+	}
+
+	var count int
+
+	if err := tx.QueryRowContext(ctx, countQuery, secondary.ID).
+		Scan(&count); err != nil {
+		return nil, err
+	}
+
+	sequence, _, head, err := allocateSequence(ctx, tx, id, count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	email := sql.NullString{String: secondary.Email, Valid: secondary.Email != ""}
+	externalID := sql.NullString{
+		String: secondary.ExternalID,
+		Valid:  secondary.ExternalID != "",
+	}
+	device := sql.NullString{String: deviceID, Valid: deviceID != ""}
+
+	for _, statement := range []struct {
+		query  string
+		values []interface{}
+	}{
+		{eventsQuery, []interface{}{id, secondary.ID, sequence}},
+		{identifiersQuery, []interface{}{id, secondary.ID}},
+		{phonesQuery, []interface{}{id, secondary.ID}},
+		{devicesQuery, []interface{}{id, secondary.ID}},
+		{receiptsQuery, []interface{}{id, secondary.ID}},
+		{emailsQuery, []interface{}{id, secondary.ID}},
+		{redirectsQuery, []interface{}{id, secondary.ID}},
+		{mergeQuery, []interface{}{secondary.ID, id, email, externalID, device}},
+		{holdQuery, []interface{}{id, secondary.ID}},
+		{deleteQuery, []interface{}{secondary.ID}},
+	} {
+		if _, err := tx.ExecContext(
+			ctx,
+			statement.query,
+			statement.values...); err != nil {
+			return nil, translateError(err)
+		}
+	}
+
+	if count > 0 {
+		if err := rechainEvents(ctx, tx, id, sequence, head); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := scanUser(tx.QueryRowContext(
+		ctx,
+		userQuery,
+		id,
+		email,
+		externalID))
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if err := populateUser(ctx, tx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u *PostgresUser) MergedInto(
+	ctx context.Context,
+	id string) (string, error) {
+
+	const query = `SELECT to_id FROM "user_merges" WHERE from_id = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.MergedInto")
+	defer span.End()
+
+	var target string
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, id).Scan(&target)
+	}); err != nil {
+		return "", translateError(err)
+	}
+
+	return target, nil
+}
+
+func lockUsers(
+	ctx context.Context,
+	tx *sql.Tx,
+	ids ...string) (map[string]models.User, error) {
+
+	const query = `SELECT id, email, external_id FROM "users" WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	users := make(map[string]models.User, len(ids))
+
+	for rows.Next() {
+		var (
+			user       models.User
+			email      sql.NullString
+			externalID sql.NullString
+		)
+
+		if err := rows.Scan(&user.ID, &email, &externalID); err != nil {
+			return nil, err
+		}
+
+		user.Email = email.String
+		user.ExternalID = externalID.String
+		users[user.ID] = user
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("User", func() {
+	var (
+		id      string
+		otherID string
+
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		user User
+	)
+
+	BeforeEach(func() {
+		id = generateID()
+		otherID = generateID()
+
+		db, mock = NewSQLMock()
+		user = NewUser(db, nil)
+	})
+
+	Describe("Merge", func() {
+		expectLock := func(rows *sqlmock.Rows) {
+			expectTenantTx(mock)
+			mock.ExpectQuery("FOR UPDATE").WillReturnRows(rows)
+		}
+
+		Context("success", func() {
+			var (
+				res *models.User
+				e   error
+			)
+
+			BeforeEach(func() {
+				tombstoneID := generateID()
+
+				expectLock(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, nil, "crm-1").
+					AddRow(otherID, "user@example.com", nil))
+				expectIntactChains(mock, id, otherID)
+				mock.ExpectQuery("SELECT count").
+					WithArgs(otherID).
+					WillReturnRows(mock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectQuery("UPDATE \"users\"").
+					WithArgs(id, 3).
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).
+						AddRow(7, nil, nil))
+				mock.ExpectExec("UPDATE \"events\"").
+					WithArgs(id, otherID, int64(5)).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec("UPDATE \"user_identifiers\"").
+					WithArgs(id, otherID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"user_phones\"").
+					WithArgs(id, otherID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"devices\"").
+					WithArgs(id, otherID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"receipts\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"email_changes\"").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE \"user_merges\"").
+					WithArgs(id, otherID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO \"user_merges\"").
+					WithArgs(otherID, id, "user@example.com", nil, nil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("SET legal_hold_at").
+					WithArgs(id, otherID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM \"users\"").
+					WithArgs(otherID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("WHERE user_id = \\$1 AND sequence >= \\$2").
+					WithArgs(id, int64(5)).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"consent_id",
+						"sequence",
+						"enabled",
+						"phone_number",
+						"occurred_at",
+						"created_at",
+						"hash",
+						"purged"}).
+						AddRow(generateID(), models.ConsentEmail, 5, true, nil, time.Now(), time.Now(), nil, false).
+						AddRow(tombstoneID, nil, 6, nil, nil, nil, nil, "purged-hash", true).
+						AddRow(generateID(), models.ConsentEmail, 7, true, nil, time.Now(), time.Now(), nil, false))
+				mock.ExpectExec("UPDATE \"events\" e\\s+SET previous_hash").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"event_tombstones\" t\\s+SET previous_hash").
+					WithArgs(
+						fmt.Sprintf("{\"%v\"}", tombstoneID),
+						sqlmock.AnyArg(),
+						"{\"purged-hash\"}").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("COALESCE").
+					WithArgs(id, "user@example.com", nil).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "user@example.com", "crm-1"))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, true, nil, time.Now()))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectCommit()
+
+				res, e = user.Merge(
+					context.TODO(),
+					id,
+					&models.UserMergeRequest{UserID: otherID})
+			})
+
+			It("returns surviving user", func() {
+				Expect(e).To(BeNil())
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Email).To(Equal("user@example.com"))
+				Expect(res.Consents).To(HaveLen(1))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("same user", func() {
+			var e error
+
+			BeforeEach(func() {
+				_, e = user.Merge(
+					context.TODO(),
+					id,
+					&models.UserMergeRequest{UserID: id})
+			})
+
+			It("returns same user error", func() {
+				Expect(e).To(MatchError(ErrSameUser))
+			})
+		})
+
+		Context("non-existent user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectLock(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(otherID, "user@example.com", nil))
+				mock.ExpectRollback()
+
+				_, e = user.Merge(
+					context.TODO(),
+					id,
+					&models.UserMergeRequest{UserID: otherID})
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+
+		Context("non-existent user to merge", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectLock(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, "user@example.com", nil))
+				mock.ExpectRollback()
+
+				_, e = user.Merge(
+					context.TODO(),
+					id,
+					&models.UserMergeRequest{UserID: otherID})
+			})
+
+			It("returns user not found error", func() {
+				Expect(e).To(MatchError(ErrUserNotFound))
+			})
+		})
+
+		Context("broken chain of user to merge", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectLock(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, "user@example.com", nil).
+					AddRow(otherID, nil, nil))
+				mock.ExpectQuery("LEFT JOIN").
+					WithArgs(pq.Array([]string{id, otherID})).
+					WillReturnRows(mock.NewRows([]string{
+						"id",
+						"event_hash",
+						"chain_start",
+						"id",
+						"consent_id",
+						"sequence",
+						"enabled",
+						"phone_number",
+						"occurred_at",
+						"created_at",
+						"previous_hash",
+						"hash",
+						"purged",
+					}).
+						AddRow(id, nil, 1, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).
+						AddRow(otherID, "deadbeef", 1, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+				mock.ExpectRollback()
+
+				_, e = user.Merge(
+					context.TODO(),
+					id,
+					&models.UserMergeRequest{UserID: otherID})
+			})
+
+			It("returns chain broken error without moving events", func() {
+				Expect(e).To(MatchError(ErrChainBroken))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("error in moving events", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectLock(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, "user@example.com", nil).
+					AddRow(otherID, nil, nil))
+				expectIntactChains(mock, id, otherID)
+				mock.ExpectQuery("SELECT count").
+					WillReturnRows(mock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery("UPDATE \"users\"").
+					WillReturnRows(mock.NewRows([]string{"event_sequence", "number", "event_hash"}).
+						AddRow(1, nil, nil))
+				mock.ExpectExec("UPDATE \"events\"").
+					WillReturnError(fmt.Errorf("update error"))
+				mock.ExpectRollback()
+
+				_, e = user.Merge(
+					context.TODO(),
+					id,
+					&models.UserMergeRequest{UserID: otherID})
+			})
+
+			It("returns error", func() {
+				Expect(e).NotTo(BeNil())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+	})
+
+	Describe("MergedInto", func() {
+		Context("merged", func() {
+			var res string
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(otherID).
+					WillReturnRows(mock.NewRows([]string{"to_id"}).AddRow(id))
+				mock.ExpectCommit()
+
+				res, _ = user.MergedInto(context.TODO(), otherID)
+			})
+
+			It("returns surviving user id", func() {
+				Expect(res).To(Equal(id))
+			})
+		})
+
+		Context("not merged", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(otherID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.MergedInto(context.TODO(), otherID)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+})
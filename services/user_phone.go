@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+	"github.com/kazimanzurrashid/consents-api-go/tracing"
+)
+
+func (u *PostgresUser) DetailByPhone(
+	ctx context.Context,
+	number string) (*models.User, error) {
+
+	const query = `
+SELECT u.id, u.email, u.external_id
+FROM "users" u
+JOIN "user_phones" p ON p.user_id = u.id
+WHERE p.number = $1`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.DetailByPhone")
+	defer span.End()
+
+	var user *models.User
+
+	if err := inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		var err error
+
+		if user, err = scanUser(tx.QueryRowContext(ctx, query, number)); err != nil {
+			return translateError(err)
+		}
+
+		return populateUser(ctx, tx, user)
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u *PostgresUser) AttachPhone(
+	ctx context.Context,
+	userID string,
+	phone models.Phone) (*models.Phone, bool, error) {
+
+	const (
+		lockQuery    = `SELECT id FROM "users" WHERE id = $1 FOR UPDATE`
+		ownerQuery   = `SELECT user_id, is_primary FROM "user_phones" WHERE number = $1`
+		demoteQuery  = `UPDATE "user_phones" SET is_primary = FALSE WHERE user_id = $1 AND is_primary`
+		promoteQuery = `UPDATE "user_phones" SET is_primary = TRUE WHERE number = $1`
+		insertQuery  = `
+INSERT INTO "user_phones"(number, user_id, is_primary)
+SELECT $1, $2, $3 OR NOT EXISTS(
+	SELECT 1 FROM "user_phones" WHERE user_id = $2 AND is_primary)
+RETURNING is_primary`
+	)
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.AttachPhone")
+	defer span.End()
+
+	tx, err := beginTx(ctx, u.db)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	var id string
+
+	if err := tx.QueryRowContext(ctx, lockQuery, userID).Scan(&id); err != nil {
+		_ = tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, ErrUserNotFound
+		}
+
+		return nil, false, err
+	}
+
+	var (
+		ownerID string
+		primary bool
+	)
+
+	err = tx.QueryRowContext(ctx, ownerQuery, phone.Number).
+		Scan(&ownerID, &primary)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return nil, false, err
+	}
+
+	created := errors.Is(err, sql.ErrNoRows)
+
+	if !created && ownerID != userID {
+		_ = tx.Rollback()
+		return nil, false, ErrPhoneNumberTaken
+	}
+
+	if !created && (primary || !phone.Primary) {
+		_ = tx.Rollback()
+		return &models.Phone{Number: phone.Number, Primary: primary}, false, nil
+	}
+
+	if phone.Primary {
+		if _, err := tx.ExecContext(ctx, demoteQuery, userID); err != nil {
+			_ = tx.Rollback()
+			return nil, false, err
+		}
+	}
+
+	if created {
+		err = tx.QueryRowContext(
+			ctx,
+			insertQuery,
+			phone.Number,
+			userID,
+			phone.Primary).Scan(&primary)
+	} else {
+		_, err = tx.ExecContext(ctx, promoteQuery, phone.Number)
+		primary = true
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, false, translateError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return &models.Phone{Number: phone.Number, Primary: primary}, created, nil
+}
+
+func (u *PostgresUser) DetachPhone(
+	ctx context.Context,
+	userID string,
+	number string) error {
+
+	const query = `DELETE FROM "user_phones" WHERE user_id = $1 AND number = $2`
+
+	ctx, span := tracing.Start(ctx, "PostgresUser.DetachPhone")
+	defer span.End()
+
+	return inTenant(ctx, u.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, userID, number)
+
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func userPhones(
+	ctx context.Context,
+	q queryer,
+	userID string) ([]models.Phone, error) {
+
+	const query = `SELECT number, is_primary FROM "user_phones" WHERE user_id = $1 ORDER BY is_primary DESC, created_at, number`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	phones := make([]models.Phone, 0)
+
+	for rows.Next() {
+		var phone models.Phone
+
+		if err := rows.Scan(&phone.Number, &phone.Primary); err != nil {
+			return nil, err
+		}
+
+		phones = append(phones, phone)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return phones, nil
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kazimanzurrashid/consents-api-go/models"
+)
+
+var _ = Describe("User", func() {
+	const number = "+14155550100"
+
+	var (
+		id string
+
+		db   *sql.DB
+		mock sqlmock.Sqlmock
+		user User
+	)
+
+	BeforeEach(func() {
+		id = generateID()
+
+		db, mock = NewSQLMock()
+		user = NewUser(db, nil)
+	})
+
+	Describe("version", func() {
+		var versions []string
+
+		detail := func(profile string, phones *sqlmock.Rows) {
+			expectTenantTx(mock)
+			mock.ExpectQuery("FROM \"users\"").
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, nil, nil))
+			mock.ExpectQuery("SELECT concat\\(").
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"profile"}).AddRow(profile))
+			mock.ExpectQuery("FROM \"events\"").
+				WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+					AddRow("0d5f6c8e-3b0a-4c55-9f0e-8a7d3f1b2c4d", models.ConsentSMS, true, number, time.Now()))
+			mock.ExpectQuery("FROM \"user_phones\"").
+				WillReturnRows(phones)
+			mock.ExpectQuery("FROM \"user_identifiers\"").
+				WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+			mock.ExpectCommit()
+
+			res, err := user.Detail(context.TODO(), id)
+
+			if err != nil {
+				panic(err)
+			}
+
+			versions = append(versions, res.Version)
+		}
+
+		BeforeEach(func() {
+			versions = nil
+
+			detail(
+				"\n\n\n+14155550100*,+14155550199",
+				mock.NewRows([]string{"number", "is_primary"}).
+					AddRow(number, true).
+					AddRow("+14155550199", false))
+
+			expectTenantTx(mock)
+			mock.ExpectExec("DELETE FROM \"user_phones\"").
+				WithArgs(id, "+14155550199").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := user.DetachPhone(context.TODO(), id, "+14155550199"); err != nil {
+				panic(err)
+			}
+
+			detail(
+				"\n\n\n+14155550100*",
+				mock.NewRows([]string{"number", "is_primary"}).AddRow(number, true))
+		})
+
+		It("changes when a non-primary phone is detached", func() {
+			Expect(versions[1]).NotTo(Equal(versions[0]))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+
+	Describe("DetailByPhone", func() {
+		Context("existent", func() {
+			var res *models.User
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("JOIN \"user_phones\"").
+					WithArgs(number).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, nil, nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}).
+						AddRow(number, true))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectCommit()
+
+				res, _ = user.DetailByPhone(context.TODO(), number)
+			})
+
+			It("returns matching user", func() {
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Email).To(BeEmpty())
+				Expect(res.Phones).To(ConsistOf(
+					models.Phone{Number: number, Primary: true}))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("JOIN \"user_phones\"").
+					WithArgs(number).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, e = user.DetailByPhone(context.TODO(), number)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("AttachPhone", func() {
+		expectLock := func() {
+			expectTenantTx(mock)
+			mock.ExpectQuery("FOR UPDATE").
+				WithArgs(id).
+				WillReturnRows(mock.NewRows([]string{"id"}).AddRow(id))
+		}
+
+		Context("new phone", func() {
+			var (
+				res     *models.Phone
+				created bool
+			)
+
+			BeforeEach(func() {
+				expectLock()
+				mock.ExpectQuery("SELECT user_id, is_primary FROM \"user_phones\"").
+					WithArgs(number).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("INSERT INTO \"user_phones\"").
+					WithArgs(number, id, false).
+					WillReturnRows(mock.NewRows([]string{"is_primary"}).AddRow(true))
+				mock.ExpectCommit()
+
+				res, created, _ = user.AttachPhone(
+					context.TODO(),
+					id,
+					models.Phone{Number: number})
+			})
+
+			It("becomes primary when user has no other phone", func() {
+				Expect(created).To(BeTrue())
+				Expect(res.Primary).To(BeTrue())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("promote existing phone", func() {
+			var (
+				res     *models.Phone
+				created bool
+			)
+
+			BeforeEach(func() {
+				expectLock()
+				mock.ExpectQuery("SELECT user_id, is_primary FROM \"user_phones\"").
+					WithArgs(number).
+					WillReturnRows(mock.NewRows([]string{"user_id", "is_primary"}).
+						AddRow(id, false))
+				mock.ExpectExec("SET is_primary = FALSE").
+					WithArgs(id).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("SET is_primary = TRUE").
+					WithArgs(number).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				res, created, _ = user.AttachPhone(
+					context.TODO(),
+					id,
+					models.Phone{Number: number, Primary: true})
+			})
+
+			It("makes it the primary phone", func() {
+				Expect(created).To(BeFalse())
+				Expect(res.Primary).To(BeTrue())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("already attached", func() {
+			var (
+				res     *models.Phone
+				created bool
+			)
+
+			BeforeEach(func() {
+				expectLock()
+				mock.ExpectQuery("SELECT user_id, is_primary FROM \"user_phones\"").
+					WithArgs(number).
+					WillReturnRows(mock.NewRows([]string{"user_id", "is_primary"}).
+						AddRow(id, true))
+				mock.ExpectRollback()
+
+				res, created, _ = user.AttachPhone(
+					context.TODO(),
+					id,
+					models.Phone{Number: number})
+			})
+
+			It("keeps existing phone unchanged", func() {
+				Expect(created).To(BeFalse())
+				Expect(res.Primary).To(BeTrue())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("attached to another user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectLock()
+				mock.ExpectQuery("SELECT user_id, is_primary FROM \"user_phones\"").
+					WithArgs(number).
+					WillReturnRows(mock.NewRows([]string{"user_id", "is_primary"}).
+						AddRow(generateID(), true))
+				mock.ExpectRollback()
+
+				_, _, e = user.AttachPhone(
+					context.TODO(),
+					id,
+					models.Phone{Number: number})
+			})
+
+			It("returns phone number taken error", func() {
+				Expect(e).To(MatchError(ErrPhoneNumberTaken))
+			})
+		})
+
+		Context("non-existent user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FOR UPDATE").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, _, e = user.AttachPhone(
+					context.TODO(),
+					id,
+					models.Phone{Number: number})
+			})
+
+			It("returns user not found error", func() {
+				Expect(e).To(MatchError(ErrUserNotFound))
+			})
+		})
+	})
+
+	Describe("DetachPhone", func() {
+		Context("attached", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("DELETE FROM \"user_phones\"").
+					WithArgs(id, number).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+
+				e = user.DetachPhone(context.TODO(), id, number)
+			})
+
+			It("does not return any error", func() {
+				Expect(e).To(BeNil())
+			})
+		})
+
+		Context("not attached", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("DELETE FROM \"user_phones\"").
+					WithArgs(id, number).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+
+				e = user.DetachPhone(context.TODO(), id, number)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+	})
+})
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -27,7 +29,7 @@ var _ = Describe("User", func() {
 		email = "user@example.com"
 
 		db, mock = NewSQLMock()
-		user = NewUser(db)
+		user = NewUser(db, nil)
 	})
 
 	Describe("Create", func() {
@@ -35,9 +37,14 @@ var _ = Describe("User", func() {
 			var res *models.User
 
 			BeforeEach(func() {
+				expectTenantTx(mock)
 				mock.ExpectExec("INSERT INTO \"users\"").
-					WithArgs(sqlmock.AnyArg(), email).
+					WithArgs(sqlmock.AnyArg(), email, nil, 0).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectCommit()
 
 				res, _ = user.Create(
 					context.TODO(),
@@ -48,6 +55,196 @@ var _ = Describe("User", func() {
 				Expect(res).NotTo(BeNil())
 				Expect(res.Email).To(Equal(email))
 				Expect(res.Consents).To(BeEmpty())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("with initial consents", func() {
+			var res *models.User
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg(), email, nil, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"events\"(.+) VALUES \\(.+\\), \\(.+\\)$").
+					WithArgs(
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						models.ConsentEmail,
+						int64(1),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						true,
+						nil,
+						nil,
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						models.ConsentSMS,
+						int64(2),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						false,
+						nil,
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentEmail, true, nil, time.Now()).
+						AddRow(generateID(), models.ConsentSMS, false, nil, time.Now()))
+				mock.ExpectCommit()
+
+				res, _ = user.Create(
+					context.TODO(),
+					&models.UserCreateRequest{
+						Email: email,
+						Consents: []models.Consent{
+							{ID: models.ConsentEmail, Enabled: true},
+							{ID: models.ConsentSMS, Enabled: false},
+						},
+					})
+			})
+
+			It("returns user with consents", func() {
+				Expect(res.Consents).To(HaveLen(2))
+				Expect(res.Version).NotTo(BeEmpty())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("sms enabled without phone", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg(), email, nil, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+
+				_, e = user.Create(
+					context.TODO(),
+					&models.UserCreateRequest{
+						Email: email,
+						Consents: []models.Consent{
+							{ID: models.ConsentSMS, Enabled: true},
+						},
+					})
+			})
+
+			It("returns phone required error", func() {
+				Expect(e).To(MatchError(ErrPhoneRequired))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("with phone only", func() {
+			const phone = "+14155550100"
+
+			var res *models.User
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg(), nil, nil, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"user_phones\"").
+					WithArgs(phone, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"events\"").
+					WithArgs(
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						models.ConsentSMS,
+						int64(1),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						true,
+						phone,
+						nil,
+						sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE \"users\" u\\s+SET event_hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentSMS, true, nil, time.Now()))
+				mock.ExpectCommit()
+
+				res, _ = user.Create(
+					context.TODO(),
+					&models.UserCreateRequest{
+						Phone: phone,
+						Consents: []models.Consent{
+							{ID: models.ConsentSMS, Enabled: true},
+						},
+					})
+			})
+
+			It("returns user with primary phone", func() {
+				Expect(res.Email).To(BeEmpty())
+				Expect(res.Phones).To(ConsistOf(
+					models.Phone{Number: phone, Primary: true}))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("phone already exists", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg(), email, nil, 0).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"user_phones\"").
+					WillReturnError(&pq.Error{
+						Code:       pqUniqueViolation,
+						Constraint: "pk_user_phones",
+					})
+				mock.ExpectRollback()
+
+				_, e = user.Create(
+					context.TODO(),
+					&models.UserCreateRequest{
+						Email: email,
+						Phone: "+14155550100",
+					})
+			})
+
+			It("returns phone number taken error", func() {
+				Expect(e).To(MatchError(ErrPhoneNumberTaken))
+				Expect(e).To(MatchError(ErrConflict))
+			})
+		})
+
+		Context("email already exists", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg(), email, nil, 0).
+					WillReturnError(&pq.Error{
+						Code:       pqUniqueViolation,
+						Constraint: "uq_email",
+					})
+				mock.ExpectRollback()
+
+				_, e = user.Create(
+					context.TODO(),
+					&models.UserCreateRequest{Email: email})
+			})
+
+			It("returns email taken error", func() {
+				Expect(e).To(MatchError(ErrEmailTaken))
+				Expect(e).To(MatchError(ErrConflict))
 			})
 		})
 
@@ -55,9 +252,11 @@ var _ = Describe("User", func() {
 			var e error
 
 			BeforeEach(func() {
+				expectTenantTx(mock)
 				mock.ExpectExec("INSERT INTO \"users\"").
-					WithArgs(sqlmock.AnyArg(), email).
+					WithArgs(sqlmock.AnyArg(), email, nil, 0).
 					WillReturnError(fmt.Errorf("insert error"))
+				mock.ExpectRollback()
 
 				_, e = user.Create(
 					context.TODO(),
@@ -68,6 +267,258 @@ var _ = Describe("User", func() {
 				Expect(e).NotTo(BeNil())
 			})
 		})
+
+		Context("error inserting events", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectExec("INSERT INTO \"users\"").
+					WithArgs(sqlmock.AnyArg(), email, nil, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO \"events\"").
+					WillReturnError(fmt.Errorf("insert error"))
+				mock.ExpectRollback()
+
+				_, e = user.Create(
+					context.TODO(),
+					&models.UserCreateRequest{
+						Email: email,
+						Consents: []models.Consent{
+							{ID: models.ConsentEmail, Enabled: true},
+						},
+					})
+			})
+
+			It("returns error without creating user", func() {
+				Expect(e).NotTo(BeNil())
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Upsert", func() {
+		var externalID string
+
+		BeforeEach(func() {
+			externalID = "crm-42"
+		})
+
+		Context("new user", func() {
+			var (
+				res     *models.User
+				created bool
+				e       error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(externalID, email).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("ON CONFLICT DO NOTHING").
+					WithArgs(sqlmock.AnyArg(), email, externalID, 0).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectCommit()
+
+				res, created, e = user.Upsert(
+					context.TODO(),
+					&models.UserCreateRequest{Email: email, ExternalID: externalID})
+			})
+
+			It("returns newly created user", func() {
+				Expect(e).To(BeNil())
+				Expect(created).To(BeTrue())
+				Expect(res.ExternalID).To(Equal(externalID))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("existing user", func() {
+			var (
+				res     *models.User
+				created bool
+				e       error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "previous@example.com", externalID))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+						AddRow(generateID(), models.ConsentSMS, true, nil, time.Now()))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectRollback()
+
+				res, created, e = user.Upsert(
+					context.TODO(),
+					&models.UserCreateRequest{Email: email, ExternalID: externalID})
+			})
+
+			It("returns existing user unchanged", func() {
+				Expect(e).To(BeNil())
+				Expect(created).To(BeFalse())
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Email).To(Equal("previous@example.com"))
+				Expect(res.Consents).To(HaveLen(1))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("existing user by email", func() {
+			var (
+				res *models.User
+				e   error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("WHERE email = \\$1").
+					WithArgs(email).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, email, nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectRollback()
+
+				res, _, e = user.Upsert(
+					context.TODO(),
+					&models.UserCreateRequest{Email: email})
+			})
+
+			It("returns existing user", func() {
+				Expect(e).To(BeNil())
+				Expect(res.ID).To(Equal(id))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("merged user", func() {
+			var (
+				res     *models.User
+				created bool
+				e       error
+			)
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("FROM \"user_merges\" m JOIN \"users\" u ON u.id = m.to_id").
+					WithArgs(externalID, email).
+					WillReturnRows(mock.NewRows([]string{"id", "email", "external_id"}).
+						AddRow(id, "survivor@example.com", nil))
+				expectProfile(mock)
+				mock.ExpectQuery("FROM \"events\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}))
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}))
+				mock.ExpectRollback()
+
+				res, created, e = user.Upsert(
+					context.TODO(),
+					&models.UserCreateRequest{Email: email, ExternalID: externalID})
+			})
+
+			It("returns surviving user", func() {
+				Expect(e).To(BeNil())
+				Expect(created).To(BeFalse())
+				Expect(res.ID).To(Equal(id))
+				Expect(res.Email).To(Equal("survivor@example.com"))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
+		Context("email taken by another user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(externalID, email).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("ON CONFLICT DO NOTHING").
+					WithArgs(sqlmock.AnyArg(), email, externalID, 0).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(externalID, email).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, _, e = user.Upsert(
+					context.TODO(),
+					&models.UserCreateRequest{Email: email, ExternalID: externalID})
+			})
+
+			It("returns email taken error", func() {
+				Expect(e).To(MatchError(ErrEmailTaken))
+			})
+		})
+
+		Context("external id taken by another user", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(externalID, "").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("ON CONFLICT DO NOTHING").
+					WithArgs(sqlmock.AnyArg(), nil, externalID, 0).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("WHERE external_id = \\$1").
+					WithArgs(externalID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("FROM \"user_merges\"").
+					WithArgs(externalID, "").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				_, _, e = user.Upsert(
+					context.TODO(),
+					&models.UserCreateRequest{ExternalID: externalID})
+			})
+
+			It("returns external id taken error", func() {
+				Expect(e).To(MatchError(ErrExternalIDTaken))
+			})
+		})
 	})
 
 	Describe("Delete", func() {
@@ -75,7 +526,10 @@ var _ = Describe("User", func() {
 			var e error
 
 			BeforeEach(func() {
-				mock.ExpectBegin()
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).AddRow(nil))
 				mock.ExpectExec("DELETE FROM \"events\"").
 					WithArgs(id).
 					WillReturnResult(sqlmock.NewResult(0, 2))
@@ -92,6 +546,43 @@ var _ = Describe("User", func() {
 			})
 		})
 
+		Context("non-existent", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WithArgs(id).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+
+				e = user.Delete(context.TODO(), id)
+			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+
+		Context("under legal hold", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).AddRow(time.Now()))
+				mock.ExpectRollback()
+
+				e = user.Delete(context.TODO(), id)
+			})
+
+			It("returns legal hold error without deleting", func() {
+				Expect(e).To(MatchError(ErrLegalHold))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+
 		Context("error in transaction begin", func() {
 			var e error
 
@@ -110,7 +601,10 @@ var _ = Describe("User", func() {
 			var e error
 
 			BeforeEach(func() {
-				mock.ExpectBegin()
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).AddRow(nil))
 				mock.ExpectExec("DELETE FROM \"events\"").
 					WithArgs(id).
 					WillReturnError(fmt.Errorf("delete error"))
@@ -128,7 +622,10 @@ var _ = Describe("User", func() {
 			var e error
 
 			BeforeEach(func() {
-				mock.ExpectBegin()
+				expectTenantTx(mock)
+				mock.ExpectQuery("SELECT legal_hold_at").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"legal_hold_at"}).AddRow(nil))
 				mock.ExpectExec("DELETE FROM \"events\"").
 					WithArgs(id).
 					WillReturnResult(sqlmock.NewResult(0, 2))
@@ -151,22 +648,33 @@ var _ = Describe("User", func() {
 			var res *models.User
 
 			BeforeEach(func() {
-				userRow := mock.NewRows([]string{"id", "email"}).
-					AddRow(id, email)
+				userRow := mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, email, nil)
 
+				expectTenantTx(mock)
 				mock.ExpectQuery("FROM \"users\"").
 					WithArgs(id).
 					WillReturnRows(userRow)
+				expectProfile(mock)
 
-				eventRows := mock.NewRows([]string{"consent_id", "enabled"}).
-					AddRow(models.ConsentEmail, true).
-					AddRow(models.ConsentSMS, false)
+				eventRows := mock.NewRows([]string{"id", "consent_id", "enabled", "phone_number", "occurred_at"}).
+					AddRow(generateID(), models.ConsentEmail, true, nil, time.Now()).
+					AddRow(generateID(), models.ConsentSMS, false, nil, time.Now())
 
 				mock.ExpectQuery("FROM \"events\"").
-					WithArgs(id, models.ConsentEmail, models.ConsentSMS).
+					WithArgs(id).
 					WillReturnRows(eventRows).
 					RowsWillBeClosed()
 
+				mock.ExpectQuery("FROM \"user_phones\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"number", "is_primary"}))
+				mock.ExpectQuery("FROM \"user_identifiers\"").
+					WithArgs(id).
+					WillReturnRows(mock.NewRows([]string{"namespace", "external_id"}).
+						AddRow("crm", "42"))
+				mock.ExpectCommit()
+
 				res, _ = user.Detail(context.TODO(), id)
 			})
 
@@ -176,38 +684,76 @@ var _ = Describe("User", func() {
 				Expect(res.Email).To(Equal(email))
 				Expect(res.Consents).NotTo(BeEmpty())
 			})
+
+			It("returns attached identifiers", func() {
+				Expect(res.Identifiers).To(Equal([]models.Identifier{
+					{Namespace: "crm", ID: "42"},
+				}))
+			})
+
+			It("returns version of latest events", func() {
+				Expect(res.Version).NotTo(BeEmpty())
+			})
 		})
 
 		Context("non-existent", func() {
 			var res *models.User
+			var e error
 
 			BeforeEach(func() {
+				expectTenantTx(mock)
 				mock.ExpectQuery("FROM \"users\"").
 					WithArgs(id).
-					WillReturnError(fmt.Errorf("no matching record"))
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
 
-				res, _ = user.Detail(context.TODO(), id)
+				res, e = user.Detail(context.TODO(), id)
 			})
 
 			It("returns nil", func() {
 				Expect(res).To(BeNil())
 			})
+
+			It("returns not found error", func() {
+				Expect(e).To(MatchError(ErrNotFound))
+			})
+		})
+
+		Context("error querying user record", func() {
+			var e error
+
+			BeforeEach(func() {
+				expectTenantTx(mock)
+				mock.ExpectQuery("FROM \"users\"").
+					WithArgs(id).
+					WillReturnError(fmt.Errorf("connection refused"))
+				mock.ExpectRollback()
+
+				_, e = user.Detail(context.TODO(), id)
+			})
+
+			It("returns error other than not found", func() {
+				Expect(e).NotTo(BeNil())
+				Expect(e).NotTo(MatchError(ErrNotFound))
+			})
 		})
 
 		Context("error querying event records", func() {
 			var e error
 
 			BeforeEach(func() {
-				userRow := mock.NewRows([]string{"id", "email"}).
-					AddRow(id, email)
+				userRow := mock.NewRows([]string{"id", "email", "external_id"}).
+					AddRow(id, email, nil)
 
+				expectTenantTx(mock)
 				mock.ExpectQuery("FROM \"users\"").
 					WithArgs(id).
 					WillReturnRows(userRow)
 
 				mock.ExpectQuery("FROM \"events\"").
-					WithArgs(id, models.ConsentEmail, models.ConsentSMS).
+					WithArgs(id).
 					WillReturnError(fmt.Errorf("query error"))
+				mock.ExpectRollback()
 
 				_, e = user.Detail(context.TODO(), id)
 			})
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test(t *testing.T) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Main Suite")
+}
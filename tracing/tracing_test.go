@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	Describe("newExporter", func() {
+		Context("not configured", func() {
+			It("returns no exporter", func() {
+				exporter, err := newExporter(context.TODO(), "")
+
+				Expect(err).To(BeNil())
+				Expect(exporter).To(BeNil())
+			})
+		})
+
+		Context("stdout", func() {
+			It("returns exporter", func() {
+				exporter, err := newExporter(context.TODO(), "stdout")
+
+				Expect(err).To(BeNil())
+				Expect(exporter).NotTo(BeNil())
+			})
+		})
+
+		Context("unsupported", func() {
+			It("returns error", func() {
+				_, err := newExporter(context.TODO(), "zipkin")
+
+				Expect(err).NotTo(BeNil())
+			})
+		})
+	})
+})